@@ -0,0 +1,255 @@
+// Package baseline records existing lint errors so that they can be ignored on later runs.
+//
+// This allows tfvet to be adopted on codebases with many existing lint errors: the current errors
+// are written to a baseline file and from then on only errors that are not in the baseline are
+// reported.
+//
+// Lint errors are matched by a fingerprint that does not include their exact location, so that
+// unrelated edits moving code around don't resurface errors already in the baseline. Instead it's
+// made up of the ruleset, rule ID, file, address of the block the error was found in and the
+// error's message.
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// DefaultPath is where the baseline is read from if no path is given.
+const DefaultPath = ".tfvet-baseline.json"
+
+// version is the version of the baseline file format.
+const version = 1
+
+// Baseline is the struct representation of a baseline file.
+type Baseline struct {
+	Version  int       `json:"version"`
+	Findings []Finding `json:"findings"`
+
+	matched map[string]map[string]int // how often each fingerprint was matched, by file linted.
+}
+
+// Finding is a single lint error recorded in the baseline. Everything but the fingerprint is
+// included only to make the baseline file easier to review.
+type Finding struct {
+	Fingerprint string `json:"fingerprint"`
+	Ruleset     string `json:"ruleset"`
+	RuleID      string `json:"rule_id"`
+	File        string `json:"file"`
+	Address     string `json:"address"`
+	Message     string `json:"message"`
+}
+
+// New returns an empty baseline.
+func New() *Baseline {
+	return &Baseline{
+		Version:  version,
+		Findings: []Finding{},
+	}
+}
+
+// Read reads the baseline file at the given path.
+func Read(path string) (*Baseline, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := &Baseline{}
+	err = json.Unmarshal(contents, baseline)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse baseline %s: %w", path, err)
+	}
+
+	if baseline.Version != version {
+		return nil, fmt.Errorf("unsupported baseline version %d; expected %d", baseline.Version, version)
+	}
+
+	return baseline, nil
+}
+
+// Write writes the baseline to the given path. Findings are sorted so that baselines can be
+// diffed easily when checked into version control.
+func (b *Baseline) Write(path string) error {
+	sort.SliceStable(b.Findings, func(i, j int) bool {
+		if b.Findings[i].File != b.Findings[j].File {
+			return b.Findings[i].File < b.Findings[j].File
+		}
+		if b.Findings[i].Address != b.Findings[j].Address {
+			return b.Findings[i].Address < b.Findings[j].Address
+		}
+		return b.Findings[i].Fingerprint < b.Findings[j].Fingerprint
+	})
+
+	contents, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(contents, '\n'), 0644)
+}
+
+// Add records all lint errors of the given result in the baseline.
+func (b *Baseline) Add(result *linter.Result) {
+	for _, lintError := range result.LintErrors {
+		b.Findings = append(b.Findings, newFinding(lintError, result.File))
+	}
+}
+
+// Filter removes all lint errors that are recorded in the baseline from the given result and
+// returns how many were removed. Filtering the same file again replaces what was matched before.
+//
+// A finding recorded n times in the baseline matches at most n lint errors, so that adding more of
+// the same mistake to a file is still reported.
+func (b *Baseline) Filter(result *linter.Result) (known int) {
+	file := normalizePath(result.Filepath)
+
+	remaining := map[string]int{}
+	for _, finding := range b.Findings {
+		if finding.File == file {
+			remaining[finding.Fingerprint]++
+		}
+	}
+
+	matched := map[string]int{}
+	lintErrors := []models.LintError{}
+	for _, lintError := range result.LintErrors {
+		fingerprint := newFinding(lintError, result.File).Fingerprint
+		if remaining[fingerprint] > 0 {
+			remaining[fingerprint]--
+			matched[fingerprint]++
+			known++
+			continue
+		}
+
+		lintErrors = append(lintErrors, lintError)
+	}
+
+	if b.matched == nil {
+		b.matched = map[string]map[string]int{}
+	}
+	b.matched[file] = matched
+
+	result.LintErrors = lintErrors
+	return known
+}
+
+// Fixed returns how many errors recorded in the baseline have since been fixed, and should be
+// called once all results have been filtered. An error is fixed if its file was filtered without
+// it being found, or if its file no longer exists. Errors in files that weren't linted are assumed
+// to still be there.
+func (b *Baseline) Fixed() (fixed int) {
+	remaining := map[string]map[string]int{}
+	for file, matched := range b.matched {
+		remaining[file] = map[string]int{}
+		for fingerprint, count := range matched {
+			remaining[file][fingerprint] = count
+		}
+	}
+
+	for _, finding := range b.Findings {
+		matched, linted := remaining[finding.File]
+		if !linted {
+			if _, err := os.Stat(finding.File); os.IsNotExist(err) {
+				fixed++
+			}
+			continue
+		}
+
+		if matched[finding.Fingerprint] > 0 {
+			matched[finding.Fingerprint]--
+			continue
+		}
+		fixed++
+	}
+
+	return fixed
+}
+
+// newFinding returns the baseline finding for a lint error found in the given file.
+func newFinding(lintError models.LintError, file *hcl.File) Finding {
+	finding := Finding{
+		Ruleset: lintError.Ruleset,
+		RuleID:  lintError.Rule.ID,
+		File:    normalizePath(lintError.Filepath),
+		Address: blockAddress(file, lintError.RuleErr.Location.Start),
+		Message: lintError.RuleErr.Suggestion,
+	}
+
+	if finding.Message == "" {
+		finding.Message = lintError.Rule.Short
+	}
+
+	digest := sha256.New()
+	for _, part := range []string{finding.Ruleset, finding.RuleID, finding.File, finding.Address, finding.Message} {
+		fmt.Fprintf(digest, "%s\x00", part)
+	}
+	finding.Fingerprint = hex.EncodeToString(digest.Sum(nil))
+
+	return finding
+}
+
+// topLevelBlocks is the schema of all top level terraform blocks.
+var topLevelBlocks = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "provider", LabelNames: []string{"name"}},
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+		{Type: "locals"},
+		{Type: "terraform"},
+		{Type: "moved"},
+	},
+}
+
+// blockAddress returns the address of the top level block the given position falls in, for example
+// "resource.aws_s3_bucket.logs". An empty address is returned for positions outside of any block.
+func blockAddress(file *hcl.File, pos models.Position) string {
+	if file == nil {
+		return ""
+	}
+
+	content, _, _ := file.Body.PartialContent(topLevelBlocks)
+
+	// Top level blocks can't overlap, so the block a position falls in is the last one starting
+	// before it.
+	address := ""
+	for _, block := range content.Blocks {
+		start := block.DefRange.Start
+		if start.Line > int(pos.Line) || (start.Line == int(pos.Line) && start.Column > int(pos.Column)) {
+			continue
+		}
+
+		address = strings.Join(append([]string{block.Type}, block.Labels...), ".")
+	}
+
+	return address
+}
+
+// normalizePath returns the path relative to the current directory when possible, so baselines
+// can be shared between machines with different checkout locations.
+func normalizePath(path string) string {
+	if filepath.IsAbs(path) {
+		cwd, err := os.Getwd()
+		if err == nil {
+			if relPath, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(relPath, "..") {
+				path = relPath
+			}
+		}
+	}
+
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,114 @@
+package baseline
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// lintResult returns a result with a lint error at the start of each given line.
+func lintResult(t *testing.T, contents string, lines ...uint32) *linter.Result {
+	file, diags := hclparse.NewParser().ParseHCL([]byte(contents), "main.tf")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	result := &linter.Result{Filepath: "main.tf", File: file}
+	for _, line := range lines {
+		result.LintErrors = append(result.LintErrors, models.LintError{
+			Filepath: "main.tf",
+			Ruleset:  "example",
+			Rule:     models.Rule{ID: "EX001"},
+			RuleErr: models.RuleError{
+				Suggestion: "Use a different resource name than example",
+				Location:   models.Range{Start: models.Position{Line: line, Column: 1}},
+			},
+		})
+	}
+
+	return result
+}
+
+func TestFilter(t *testing.T) {
+	baseline := New()
+	baseline.Add(lintResult(t, `
+resource "a" "example" {}
+resource "b" "example" {}
+`, 2, 3))
+
+	if baseline.Findings[0].Address != "resource.a.example" {
+		t.Fatalf("unexpected address %q", baseline.Findings[0].Address)
+	}
+
+	// Moving blocks around shouldn't matter, but errors in new blocks and additional errors in
+	// already baselined blocks should be reported.
+	result := lintResult(t, `
+resource "c" "example" {}
+
+
+resource "a" "example" {
+  name = "test"
+}
+`, 2, 5, 6)
+
+	known := baseline.Filter(result)
+	fixed := baseline.Fixed()
+	if known != 1 || fixed != 1 {
+		t.Fatalf("expected 1 known and 1 fixed error; got %d known and %d fixed", known, fixed)
+	}
+
+	if len(result.LintErrors) != 2 || result.LintErrors[0].RuleErr.Location.Start.Line != 2 {
+		t.Fatalf("unexpected remaining lint errors %+v", result.LintErrors)
+	}
+}
+
+func TestFixed(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	for _, file := range []string{"main.tf", "unlinted.tf"} {
+		err = ioutil.WriteFile(file, []byte{}, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	baseline := New()
+	baseline.Add(lintResult(t, `
+resource "a" "example" {}
+resource "b" "example" {}
+`, 2, 3))
+	baseline.Findings = append(baseline.Findings,
+		Finding{Fingerprint: "deleted", File: "deleted.tf"},
+		Finding{Fingerprint: "unlinted", File: "unlinted.tf"},
+	)
+
+	// Errors in files that no longer exist are fixed, errors in existing files that weren't linted
+	// aren't.
+	if fixed := baseline.Fixed(); fixed != 1 {
+		t.Fatalf("expected 1 fixed error before linting; got %d", fixed)
+	}
+
+	// Only the last time a file is filtered counts, as in watch mode.
+	baseline.Filter(lintResult(t, `
+resource "a" "example" {}
+`))
+	baseline.Filter(lintResult(t, `
+resource "a" "example" {}
+`, 2))
+
+	if fixed := baseline.Fixed(); fixed != 2 {
+		t.Fatalf("expected 2 fixed errors; got %d", fixed)
+	}
+}
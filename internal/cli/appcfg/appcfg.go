@@ -4,10 +4,12 @@ package appcfg
 
 import (
 	"errors"
-	"io/ioutil"
+	"fmt"
 	"os"
 	"strings"
 
+	"github.com/clintjedwards/tfvet/v2/internal/core"
+	"github.com/clintjedwards/tfvet/v2/internal/utils"
 	models "github.com/clintjedwards/tfvet/v2/sdk"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclsimple"
@@ -18,13 +20,21 @@ import (
 // We wrap this so that we can add other attributes in here.
 type Appcfg struct {
 	Rulesets []models.Ruleset `hcl:"ruleset,block"`
+
+	// lock is held if the config was retrieved with GetConfigLocked.
+	lock *fileLock
 }
 
-// CreateNewFile creates a new empty config file
+// CreateNewFile creates a new empty config file. If the file already exists it is left untouched.
 func CreateNewFile() error {
 	cfgFile := hclwrite.NewEmptyFile()
 
-	f, err := os.Create(ConfigFilePath())
+	// We don't truncate here since another tfvet process might have created and written to the
+	// file in the meantime.
+	f, err := os.OpenFile(ConfigFilePath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -47,9 +57,69 @@ func GetConfig() (*Appcfg, error) {
 		return nil, err
 	}
 
+	hclFile.addBuiltinRuleset()
+
 	return hclFile, nil
 }
 
+// addBuiltinRuleset makes sure the config includes the ruleset built into tfvet, with the rules of
+// the running version of tfvet. User settings, like which rules are enabled, are carried over.
+// Since the built-in ruleset is always present, AddRuleset rejects its name; another ruleset by
+// that name can only come from a config written by an older version of tfvet. That ruleset is
+// kept, and the built-in one is left out rather than sharing its name.
+func (appcfg *Appcfg) addBuiltinRuleset() {
+	builtin := core.Ruleset()
+
+	for index, ruleset := range appcfg.Rulesets {
+		if ruleset.Name != builtin.Name {
+			continue
+		}
+
+		if !core.IsBuiltin(ruleset) {
+			return
+		}
+
+		builtin.Enabled = ruleset.Enabled
+		builtin.Rules = MergeRules(ruleset.Rules, builtin.Rules)
+		appcfg.Rulesets[index] = builtin
+		return
+	}
+
+	appcfg.Rulesets = append([]models.Ruleset{builtin}, appcfg.Rulesets...)
+}
+
+// GetConfigLocked parses the on disk config file like GetConfig, but first takes an exclusive lock
+// that prevents other tfvet processes from changing the config until Unlock is called.
+// This should be used for any command that modifies the config, so that concurrent invocations
+// don't overwrite each other's changes.
+func GetConfigLocked() (*Appcfg, error) {
+	lock, err := lockFile(LockFilePath())
+	if err != nil {
+		return nil, fmt.Errorf("could not lock config: %w", err)
+	}
+
+	hclFile, err := GetConfig()
+	if err != nil {
+		_ = lock.unlock()
+		return nil, err
+	}
+	hclFile.lock = lock
+
+	return hclFile, nil
+}
+
+// Unlock releases the lock taken by GetConfigLocked. It is a no-op for configs retrieved without a
+// lock.
+func (appcfg *Appcfg) Unlock() error {
+	if appcfg.lock == nil {
+		return nil
+	}
+
+	err := appcfg.lock.unlock()
+	appcfg.lock = nil
+	return err
+}
+
 // RepositoryExists checks to see if the config already has an entry for the repository in the config.
 func (appcfg *Appcfg) RepositoryExists(repo string) bool {
 	for _, ruleset := range appcfg.Rulesets {
@@ -114,41 +184,36 @@ func (appcfg *Appcfg) RulesetExists(name string) bool {
 	return false
 }
 
-// UpsertRule adds a new rule to an already established ruleset if it does not exist. If the rule
-// already exists it simply updates the rule with the newer information.
-// Returns an error if the ruleset is not found or there was an error writing to the file.
-func (appcfg *Appcfg) UpsertRule(rulesetName string, newRule models.Rule) error {
-	for index, ruleset := range appcfg.Rulesets {
-		if ruleset.Name != rulesetName {
-			continue
-		}
+// MergeRules returns the new rules with user settings carried over from the matching existing rules.
+// A rule matches if its ID is either the ID or one of the aliases of the new rule. This allows a rule
+// to change its ID without losing user settings.
+// Existing rules without a matching new rule are dropped.
+func MergeRules(rules []models.Rule, newRules []models.Rule) []models.Rule {
+	merged := []models.Rule{}
 
-		for ruleIndex, rule := range ruleset.Rules {
-			if rule.ID == newRule.ID {
+	for _, newRule := range newRules {
+		for _, rule := range rules {
+			if !RuleMatches(newRule, rule.ID) {
+				continue
+			}
 
-				// Keep user settings for updated rule
-				newRule.Enabled = rule.Enabled
+			// Keep user settings for updated rule
+			newRule.Enabled = rule.Enabled
 
-				appcfg.Rulesets[index].Rules[ruleIndex] = newRule
-				err := appcfg.writeConfig()
-				if err != nil {
-					return err
+			// Carry over any IDs the rule was previously known by.
+			for _, alias := range append(rule.Aliases, rule.ID) {
+				if !RuleMatches(newRule, alias) {
+					newRule.Aliases = append(newRule.Aliases, alias)
 				}
-				return nil
 			}
-		}
 
-		ruleset.Rules = append(ruleset.Rules, newRule)
-		appcfg.Rulesets[index] = ruleset
-		err := appcfg.writeConfig()
-		if err != nil {
-			return err
+			break
 		}
 
-		return nil
+		merged = append(merged, newRule)
 	}
 
-	return errors.New("ruleset not found")
+	return merged
 }
 
 // SetRulesetEnabled changes the enabled attribute on a ruleset.
@@ -180,7 +245,7 @@ func (appcfg *Appcfg) SetRuleEnabled(ruleset, rule string, enabled bool) error {
 		}
 
 		for index, r := range rs.Rules {
-			if r.ID != rule {
+			if !RuleMatches(r, rule) {
 				continue
 			}
 
@@ -212,7 +277,7 @@ func (appcfg *Appcfg) GetRuleset(name string) (models.Ruleset, error) {
 	return models.Ruleset{}, errors.New("ruleset not found")
 }
 
-// GetRule returns the rule object of a given name.
+// GetRule returns the rule object of a given ID. Previous IDs of a rule also resolve to it.
 // Returns an error if ruleset or rule isn't found.
 func (appcfg *Appcfg) GetRule(rulesetName, ruleID string) (models.Rule, error) {
 	for _, ruleset := range appcfg.Rulesets {
@@ -221,7 +286,7 @@ func (appcfg *Appcfg) GetRule(rulesetName, ruleID string) (models.Rule, error) {
 		}
 
 		for _, rule := range ruleset.Rules {
-			if rule.ID != ruleID {
+			if !RuleMatches(rule, ruleID) {
 				continue
 			}
 
@@ -234,16 +299,30 @@ func (appcfg *Appcfg) GetRule(rulesetName, ruleID string) (models.Rule, error) {
 	return models.Rule{}, errors.New("ruleset not found")
 }
 
+// RuleMatches determines if the given ID refers to the rule, either directly or through one of
+// its aliases. IDs are matched case insensitively.
+func RuleMatches(rule models.Rule, id string) bool {
+	if strings.EqualFold(rule.ID, id) {
+		return true
+	}
+
+	for _, alias := range rule.Aliases {
+		if strings.EqualFold(alias, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // writeConfig takes the current representation of config and writes it to the file.
+//
+// The config is written atomically, so that an interrupted write can never leave behind a
+// partially written config.
 func (appcfg *Appcfg) writeConfig() error {
 	f := hclwrite.NewEmptyFile()
 
 	gohcl.EncodeIntoBody(appcfg, f.Body())
 
-	err := ioutil.WriteFile(ConfigFilePath(), f.Bytes(), 0644)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return utils.WriteFileAtomic(ConfigFilePath(), f.Bytes(), 0644)
 }
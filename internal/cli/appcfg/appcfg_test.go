@@ -0,0 +1,150 @@
+package appcfg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+// useConfigPath points the config to a temporary directory for the rest of the test.
+func useConfigPath(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	previous, ok := os.LookupEnv("TFVET_CONFIG_PATH")
+	os.Setenv("TFVET_CONFIG_PATH", dir)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("TFVET_CONFIG_PATH", previous)
+			return
+		}
+		os.Unsetenv("TFVET_CONFIG_PATH")
+	})
+
+	return dir
+}
+
+func TestGetRule(t *testing.T) {
+	cfg := &Appcfg{Rulesets: []models.Ruleset{
+		{Name: "example", Rules: []models.Rule{
+			{ID: "EX001", Aliases: []string{"89cd4"}},
+			{ID: "EX002"},
+		}},
+		{Name: "other", Rules: []models.Rule{{ID: "EX001"}}},
+	}}
+
+	tests := map[string]struct {
+		ruleset string
+		id      string
+		want    string
+	}{
+		"id":                  {ruleset: "example", id: "EX002", want: "EX002"},
+		"id in any case":      {ruleset: "example", id: "ex001", want: "EX001"},
+		"alias":               {ruleset: "example", id: "89CD4", want: "EX001"},
+		"alias of other rule": {ruleset: "other", id: "89cd4"},
+		"unknown id":          {ruleset: "example", id: "EX003"},
+		"unknown ruleset":     {ruleset: "missing", id: "EX001"},
+	}
+
+	for name, test := range tests {
+		rule, err := cfg.GetRule(test.ruleset, test.id)
+		switch {
+		case test.want == "" && err == nil:
+			t.Errorf("%s: expected no rule to be found; got %s", name, rule.ID)
+		case test.want != "" && err != nil:
+			t.Errorf("%s: expected rule %s; got %v", name, test.want, err)
+		case test.want != "" && rule.ID != test.want:
+			t.Errorf("%s: expected rule %s; got %s", name, test.want, rule.ID)
+		}
+	}
+}
+
+func TestMergeRules(t *testing.T) {
+	rules := []models.Rule{
+		{ID: "89cd4", Enabled: false},
+		{ID: "EX002", Enabled: false},
+		{ID: "EX003", Enabled: true},
+	}
+
+	merged := MergeRules(rules, []models.Rule{
+		{ID: "EX001", Aliases: []string{"89CD4"}, Enabled: true, Name: "Renamed"},
+		{ID: "EX002", Enabled: true, Name: "Updated"},
+		{ID: "EX004", Enabled: true},
+	})
+
+	if len(merged) != 3 {
+		t.Fatalf("expected removed rules to be dropped; got %+v", merged)
+	}
+
+	// User settings are kept for rules that changed their ID as well as for updated ones.
+	if merged[0].Enabled || merged[0].Name != "Renamed" || len(merged[0].Aliases) != 1 {
+		t.Errorf("expected EX001 to be updated and stay disabled; got %+v", merged[0])
+	}
+	if merged[1].Enabled || merged[1].Name != "Updated" {
+		t.Errorf("expected EX002 to be updated and stay disabled; got %+v", merged[1])
+	}
+	if !merged[2].Enabled {
+		t.Errorf("expected the new rule EX004 to be added as is; got %+v", merged[2])
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	dir := useConfigPath(t)
+
+	err := CreateNewFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cfg.AddRuleset(models.Ruleset{Name: "Example", Version: "0.0.1", Enabled: true,
+		Rules: []models.Rule{{ID: "EX001", Name: "First"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := GetConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ruleset, err := written.GetRuleset("example")
+	if err != nil || ruleset.Version != "0.0.1" || len(ruleset.Rules) != 1 {
+		t.Fatalf("expected the ruleset to be written; got %+v, %v", ruleset, err)
+	}
+
+	// A config that can't be replaced, here because a directory is in its way, fails the write
+	// without leaving temporary files behind.
+	err = os.Remove(ConfigFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Join(ConfigFilePath(), "keep"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ruleset.Version = "0.0.2"
+	err = written.UpdateRuleset(ruleset)
+	if err == nil {
+		t.Fatal("expected writing the config to fail")
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("expected temporary files to be removed; found %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package appcfg
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileLock is an advisory lock held on a file for the lifetime of a read-modify-write sequence.
+type fileLock struct {
+	file *os.File
+}
+
+// lockFile takes an exclusive advisory lock on the file at path, creating it if necessary.
+// It blocks until the lock is available.
+func lockFile(path string) (*fileLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &fileLock{file: file}, nil
+}
+
+// unlock releases the lock.
+func (l *fileLock) unlock() error {
+	defer l.file.Close()
+	return syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+}
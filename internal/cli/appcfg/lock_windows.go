@@ -0,0 +1,28 @@
+package appcfg
+
+import (
+	"os"
+)
+
+// fileLock is an advisory lock held on a file for the lifetime of a read-modify-write sequence.
+//
+// TODO(clintjedwards): Advisory locking is not implemented on windows; concurrent tfvet processes
+// can still race on read-modify-write sequences there. Writes themselves remain atomic.
+type fileLock struct {
+	file *os.File
+}
+
+// lockFile opens the file at path, creating it if necessary. No lock is taken on windows.
+func lockFile(path string) (*fileLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileLock{file: file}, nil
+}
+
+// unlock releases the lock.
+func (l *fileLock) unlock() error {
+	return l.file.Close()
+}
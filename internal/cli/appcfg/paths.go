@@ -3,6 +3,7 @@ package appcfg
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/clintjedwards/tfvet/v2/internal/config"
 	"github.com/mitchellh/go-homedir"
@@ -15,13 +16,45 @@ const (
 	// configFileName is the name of the config file that stores app information.
 	configFileName string = ".tfvet.hcl"
 
+	// lockFileName is the name of the file used to coordinate changes to the config file between
+	// concurrently running tfvet processes.
+	lockFileName string = ".tfvet.lock"
+
 	// repoDirName is the name of the directory that stores the raw ruleset folder.
 	repoDirName string = "repo"
 
 	// rulesDirName is the name of the directory
 	rulesDirName string = "rules"
+
+	// cacheDirName is the name of the config directory that holds cached lint results.
+	cacheDirName string = "cache"
+
+	// buildCacheFileName is the name of the file that records the source hash of each rule at the
+	// time it was last compiled.
+	buildCacheFileName string = "build.hcl"
+
+	// declarativeRuleExt is the extension of the files declarative rules are stored in. It can't
+	// be mistaken for other files of the ruleset directory, like the build cache, since rule IDs
+	// are alphanumeric.
+	declarativeRuleExt string = ".rule.hcl"
 )
 
+// reservedRuleIDs are the rule IDs that can't be used, since rules are stored under their ID next
+// to the repository and build cache of their ruleset.
+var reservedRuleIDs = []string{repoDirName, strings.TrimSuffix(buildCacheFileName, ".hcl")}
+
+// IsReservedRuleID returns whether the given rule ID is reserved by tfvet. IDs are compared case
+// insensitively, since not all file systems tell them apart.
+func IsReservedRuleID(id string) bool {
+	for _, reserved := range reservedRuleIDs {
+		if strings.EqualFold(id, reserved) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Config paths
 
 // ConfigPath returns the absolute config path determined by environment variable.
@@ -46,6 +79,18 @@ func ConfigFilePath() string {
 	return fmt.Sprintf("%s/%s", ConfigPath(), configFileName)
 }
 
+// LockFilePath returns the absolute path of the config lock file.
+// By default this is ~/.tfvet.d/.tfvet.lock
+func LockFilePath() string {
+	return fmt.Sprintf("%s/%s", ConfigPath(), lockFileName)
+}
+
+// CachePath returns the absolute directory path of the directory that stores cached lint results.
+// By default this is ~/.tfvet.d/cache
+func CachePath() string {
+	return fmt.Sprintf("%s/%s", ConfigPath(), cacheDirName)
+}
+
 // RulesetsPath returns the absolute directory path of the directory that stores rulesets.
 // By default this is ~/.tfvet.d/rulesets.d
 //
@@ -61,6 +106,22 @@ func RulesetPath(ruleset string) string {
 	return fmt.Sprintf("%s/%s", RulesetsPath(), ruleset)
 }
 
+// StagingRuleset returns the name of the directory a ruleset is assembled in while being added or
+// updated. Staged rulesets live alongside installed ones so that all ruleset path functions work on
+// them and they can be moved into place with a single rename. The leading dot can't appear in
+// ruleset names, so staged rulesets never collide with installed ones.
+// By default the staging directory is ~/.tfvet.d/rulesets.d/.<ruleset>.staging
+func StagingRuleset(ruleset string) string {
+	return fmt.Sprintf(".%s.staging", ruleset)
+}
+
+// BackupRuleset returns the name of the directory the installed version of a ruleset is kept in
+// while it is being replaced.
+// By default the backup directory is ~/.tfvet.d/rulesets.d/.<ruleset>.backup
+func BackupRuleset(ruleset string) string {
+	return fmt.Sprintf(".%s.backup", ruleset)
+}
+
 // RepoPath returns the absolute path for the repo directory inside of a specific ruleset.
 // By default this is ~/.tfvet.d/rulesets.d/<ruleset>/repo
 func RepoPath(ruleset string) string {
@@ -79,3 +140,23 @@ func RepoRulesPath(ruleset string) string {
 func RulePath(ruleset, ruleID string) string {
 	return fmt.Sprintf("%s/%s", RulesetPath(ruleset), ruleID)
 }
+
+// DeclarativeRuleFileName returns the name of the file a declarative rule is stored in within a
+// ruleset directory.
+func DeclarativeRuleFileName(ruleID string) string {
+	return ruleID + declarativeRuleExt
+}
+
+// DeclarativeRulePath returns the absolute path for a declarative rule within a ruleset directory.
+// Declarative rules are run by tfvet itself, so they are stored as their definition instead of a
+// binary.
+// By default this is ~/.tfvet.d/rulesets.d/<ruleset>/<ruleID>.rule.hcl
+func DeclarativeRulePath(ruleset, ruleID string) string {
+	return RulePath(ruleset, DeclarativeRuleFileName(ruleID))
+}
+
+// BuildCacheFilePath returns the absolute path of the build cache file for a ruleset.
+// By default this is ~/.tfvet.d/rulesets.d/<ruleset>/build.hcl
+func BuildCacheFilePath(ruleset string) string {
+	return fmt.Sprintf("%s/%s", RulesetPath(ruleset), buildCacheFileName)
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/clintjedwards/polyfmt"
+	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+	"github.com/spf13/cobra"
+)
+
+// cmdCache is a subcommand for the lint result cache
+var cmdCache = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the lint result cache",
+	Long: `Manage the lint result cache.
+
+tfvet caches the results of running each rule against each file, so that linting files which haven't
+changed since they were last linted doesn't need to run any rules. Results are stored in the cache
+directory within the tfvet config path and are invalidated automatically whenever a file, rule or
+rule configuration changes.`,
+}
+
+var cmdCacheClean = &cobra.Command{
+	Use:   "clean",
+	Short: "Removes all cached lint results",
+	Long:  `Removes all cached lint results, causing all rules to run again on the next lint.`,
+	Args:  cobra.NoArgs,
+	RunE:  runCacheClean,
+}
+
+func runCacheClean(cmd *cobra.Command, _ []string) error {
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	clifmt, err := polyfmt.NewFormatter(polyfmt.Mode(format))
+	if err != nil {
+		return err
+	}
+
+	clifmt.Print("Cleaning cache", polyfmt.Pretty)
+
+	err = os.RemoveAll(appcfg.CachePath())
+	if err != nil {
+		clifmt.PrintErr(fmt.Sprintf("could not clean cache: %v", err))
+		clifmt.Finish()
+		return err
+	}
+
+	clifmt.PrintSuccess(fmt.Sprintf("Removed cache %s", appcfg.CachePath()))
+	clifmt.Finish()
+	return nil
+}
+
+func init() {
+	cmdCache.AddCommand(cmdCacheClean)
+	RootCmd.AddCommand(cmdCache)
+}
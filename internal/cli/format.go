@@ -7,6 +7,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
 	models "github.com/clintjedwards/tfvet/v2/sdk"
 	"github.com/olekukonko/tablewriter"
 )
@@ -17,32 +18,73 @@ import (
 // https://doc.rust-lang.org/edition-guide/rust-2018/the-compiler/improved-error-messages.html
 func formatLintError(lintErr models.LintError) string {
 	const lintErrorTmpl = `Error[{{.ID}}]: {{.Short}}
-  --> {{.Filepath}}:{{.StartLine}}:{{.StartColumn}}
+  --> {{.Location}}
 {{.LineText}}
   = additional information:
 {{.Metadata}}
 For more information about this error, try running ` + "`tfvet rule describe {{.Ruleset}} {{.ID}}`."
 
+	// Lint errors without a location apply to the file as a whole.
+	location := lintErr.Filepath
+	lineText := ""
+	if start := lintErr.RuleErr.Location.Start; start.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", lintErr.Filepath, start.Line, start.Column)
+		lineText = formatLineTable(lintErr.Line, int(start.Line))
+	}
+
 	var tpl bytes.Buffer
 	t := template.Must(template.New("tmp").Parse(lintErrorTmpl))
 	_ = t.Execute(&tpl, struct {
-		ID          string
-		Short       string
-		Filepath    string
-		StartLine   int
-		StartColumn int
-		LineText    string
-		Metadata    string
-		Ruleset     string
+		ID       string
+		Short    string
+		Location string
+		LineText string
+		Metadata string
+		Ruleset  string
+	}{
+		ID:       lintErr.Rule.ID,
+		Short:    lintErr.Rule.Short,
+		Location: location,
+		LineText: lineText,
+		Metadata: formatAdditionalInfo(lintErr),
+		Ruleset:  lintErr.Ruleset,
+	})
+
+	return tpl.String()
+}
+
+// formatRuleDiagnostic formats a problem a rule ran into while linting. These are kept apart from
+// lint errors since they point at a bug in the rule rather than in the linted file.
+func formatRuleDiagnostic(diagnostic linter.RuleDiagnostic) string {
+	const ruleDiagnosticTmpl = `Rule {{.Severity}}[{{.ID}}]: {{.Message}}
+  --> {{.Location}}
+{{- if .Incomplete}}
+  = the rule could not check all of the file, so its lint errors may be incomplete
+{{- end}}
+This is a problem with the rule itself; consider reporting it to the maintainers of the {{.Ruleset}} ruleset.`
+
+	location := diagnostic.Filepath
+	if diagnostic.Diagnostic.Location != nil {
+		location = fmt.Sprintf("%s:%d:%d", diagnostic.Filepath,
+			diagnostic.Diagnostic.Location.Start.Line, diagnostic.Diagnostic.Location.Start.Column)
+	}
+
+	var tpl bytes.Buffer
+	t := template.Must(template.New("tmp").Parse(ruleDiagnosticTmpl))
+	_ = t.Execute(&tpl, struct {
+		Severity   string
+		ID         string
+		Message    string
+		Location   string
+		Incomplete bool
+		Ruleset    string
 	}{
-		ID:          lintErr.Rule.ID,
-		Short:       lintErr.Rule.Short,
-		Filepath:    lintErr.Filepath,
-		StartLine:   int(lintErr.RuleErr.Location.Start.Line),
-		StartColumn: int(lintErr.RuleErr.Location.Start.Column),
-		LineText:    formatLineTable(lintErr.Line, int(lintErr.RuleErr.Location.Start.Line)),
-		Metadata:    formatAdditionalInfo(lintErr),
-		Ruleset:     lintErr.Ruleset,
+		Severity:   string(diagnostic.Diagnostic.Severity),
+		ID:         diagnostic.Rule.ID,
+		Message:    diagnostic.Diagnostic.Message,
+		Location:   location,
+		Incomplete: diagnostic.Diagnostic.Severity == models.DiagnosticError,
+		Ruleset:    diagnostic.Ruleset,
 	})
 
 	return tpl.String()
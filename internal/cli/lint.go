@@ -1,26 +1,21 @@
 package cli
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/clintjedwards/polyfmt"
+	"github.com/clintjedwards/tfvet/v2/internal/baseline"
 	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
-	tfvetPlugin "github.com/clintjedwards/tfvet/v2/internal/plugin"
-	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
-	"github.com/clintjedwards/tfvet/v2/internal/utils"
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	"github.com/clintjedwards/tfvet/v2/internal/sarif"
 	models "github.com/clintjedwards/tfvet/v2/sdk"
-	"github.com/hashicorp/go-hclog"
-	"github.com/hashicorp/go-plugin"
-	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/mitchellh/go-homedir"
 	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/spf13/cobra"
@@ -31,21 +26,69 @@ var cmdLint = &cobra.Command{
 	Use:   "lint [paths...]",
 	Short: "Runs the terraform linter",
 	Long: `Runs the terraform linter for all enabled rules, grabbing all terraform files in current
-directory by default.
-
-Accepts multiple paths delimited by a space.
+directory by default. Terraform files are configuration files (.tf and .tf.json) and variable
+definitions files (.tfvars); rules choose which of these they are run against.
+
+Accepts multiple paths delimited by a space. A path of "-" reads a terraform file from stdin; use
+--stdin-filename to set the file name lint errors are reported against.
+
+--sarif writes the lint errors found, along with problems rules ran into, to the given file in the
+SARIF format read by code scanning tools.
+
+To adopt tfvet on a codebase with many existing lint errors, record them with --write-baseline.
+Later runs with --baseline only report lint errors that aren't in the baseline, along with how many
+of the recorded errors have since been fixed. --baseline reads ` + baseline.DefaultPath + ` unless a
+path is given as --baseline=<path>.
+
+On large repositories --changed-since limits linting to the files that changed in the local git
+repository since it diverged from the given revision, including uncommitted changes. Adding
+--changed-lines further limits lint errors to the lines that changed. Rules are still run against
+whole files, so they keep the context of the entire file. Neither can be used when reading from
+stdin, since there's no file in the repository to compare against.
+
+With --plan, tfvet runs plan rules against a terraform plan in JSON format, created with
+` + "`terraform show -json <planfile> > plan.json`" + `. Plans hold the final values of resources
+after modules, count and for_each are expanded. Lint errors point at the configuration of the
+resource they were found in, which is looked up in the current directory or the directory given.
+
+Rules can be selected by their metadata: --tags only runs rules with at least one of the given tags
+or categories, and --exclude-category skips rules in the given categories (security, cost, style or
+correctness). Rules that target specific terraform providers are only run against modules that use
+one of them.
+
+With --watch, tfvet keeps running after the first pass and re-lints files as they change. Edits to
+the tfvet config file (like enabling or disabling rules) are picked up without restarting.
 `,
 	RunE: runLint,
 	Example: `$ tfvet lint
 $ tfvet lint myfile.tf
-$ tfvet line somefile.tf manyfilesfolder/*`,
+$ tfvet line somefile.tf manyfilesfolder/*
+$ tfvet lint --watch
+$ git show HEAD:main.tf | tfvet lint - --stdin-filename main.tf
+$ tfvet lint --sarif tfvet.sarif
+$ tfvet lint --write-baseline .tfvet-baseline.json
+$ tfvet lint --baseline
+$ tfvet lint --changed-since origin/main --changed-lines
+$ tfvet lint --tags security --exclude-category style
+$ tfvet lint --plan plan.json`,
 }
 
+// stdinPath is the path argument that makes lint read a file from stdin.
+const stdinPath = "-"
+
 // state contains a bunch of useful state information for the add cli function. This is mostly
 // just for convenience.
 type state struct {
-	fmt polyfmt.Formatter
-	cfg *appcfg.Appcfg
+	fmt    polyfmt.Formatter
+	cfg    *appcfg.Appcfg
+	linter *linter.Linter
+
+	baseline      *baseline.Baseline // lint errors in the baseline are not reported.
+	writeBaseline *baseline.Baseline // records all lint errors found.
+	sarif         *sarif.Log         // records the lint errors reported.
+
+	changes      *changeSet // if set only lint errors in changed files are reported.
+	changedLines bool       // only report lint errors on changed lines.
 }
 
 // newState returns a new state object with the fmt initialized
@@ -65,8 +108,9 @@ func newState(initialFmtMsg, format string) (*state, error) {
 	}
 
 	return &state{
-		fmt: clifmt,
-		cfg: cfg,
+		fmt:    clifmt,
+		cfg:    cfg,
+		linter: linter.New(cfg.Rulesets),
 	}, nil
 }
 
@@ -115,7 +159,7 @@ func (s *state) getTerraformFiles(paths []string) ([]string, error) {
 		}
 
 		for _, file := range globFiles {
-			if strings.HasSuffix(file, ".tf") {
+			if isTerraformFile(file) {
 				tfFiles = append(tfFiles, file)
 			}
 		}
@@ -124,6 +168,13 @@ func (s *state) getTerraformFiles(paths []string) ([]string, error) {
 	return tfFiles, nil
 }
 
+// isTerraformFile returns whether the file at the given path is a terraform file. This includes
+// configuration files in either the native or the JSON syntax and variable definitions files.
+func isTerraformFile(path string) bool {
+	return strings.HasSuffix(path, ".tf") || strings.HasSuffix(path, ".tf.json") ||
+		strings.HasSuffix(path, ".tfvars")
+}
+
 func runLint(cmd *cobra.Command, args []string) error {
 	format, err := cmd.Flags().GetString("format")
 	if err != nil {
@@ -131,14 +182,175 @@ func runLint(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	watch, err := cmd.Flags().GetBool("watch")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	stdinFilename, err := cmd.Flags().GetString("stdin-filename")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	baselinePath, err := cmd.Flags().GetString("baseline")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	writeBaselinePath, err := cmd.Flags().GetString("write-baseline")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	sarifPath, err := cmd.Flags().GetString("sarif")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	changedSince, err := cmd.Flags().GetString("changed-since")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	changedLines, err := cmd.Flags().GetBool("changed-lines")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	noCache, err := cmd.Flags().GetBool("no-cache")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	tags, err := cmd.Flags().GetStringSlice("tags")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	excludeCategories, err := cmd.Flags().GetStringSlice("exclude-category")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
+	planPath, err := cmd.Flags().GetString("plan")
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+
 	state, err := newState("Running Linter", format)
 	if err != nil {
 		log.Print(err)
 		return err
 	}
+	defer state.linter.Close()
+
+	state.linter.OnRule = func(ruleset string, rule models.Rule, path string) {
+		state.fmt.Print(fmt.Sprintf("%q ruleset linting %q for rule %q",
+			strings.ToLower(ruleset), filepath.Base(path), strings.ToLower(rule.Name)))
+	}
+
+	if !noCache {
+		state.linter.Cache = linter.NewCache(appcfg.CachePath())
+	}
+
+	for _, category := range excludeCategories {
+		if !models.Category(strings.ToLower(category)).IsValid() {
+			errText := fmt.Sprintf("unknown category %q; accepted values are %v", category, models.Categories)
+			state.fmt.PrintErr(errText)
+			state.fmt.Finish()
+			return errors.New(errText)
+		}
+	}
+	state.linter.Tags = tags
+	state.linter.ExcludeCategories = excludeCategories
+
+	state.linter.Logger, err = newRuleLogger(cmd)
+	if err != nil {
+		errText := fmt.Sprintf("could not set up rule logs: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	if baselinePath != "" && writeBaselinePath != "" {
+		state.fmt.PrintErr("Cannot use --baseline and --write-baseline together")
+		state.fmt.Finish()
+		return errors.New("cannot use --baseline and --write-baseline together")
+	}
+
+	if baselinePath != "" {
+		state.baseline, err = baseline.Read(baselinePath)
+		if err != nil {
+			errText := fmt.Sprintf("could not read baseline: %v", err)
+			state.fmt.PrintErr(errText)
+			state.fmt.Finish()
+			return errors.New(errText)
+		}
+	}
+
+	if writeBaselinePath != "" {
+		state.writeBaseline = baseline.New()
+	}
+
+	if sarifPath != "" {
+		state.sarif = sarif.New(strings.Split(appVersion, "_")[0])
+	}
+
+	if changedLines && changedSince == "" {
+		state.fmt.PrintErr("--changed-lines requires --changed-since")
+		state.fmt.Finish()
+		return errors.New("--changed-lines requires --changed-since")
+	}
+
+	if changedSince != "" && watch {
+		state.fmt.PrintErr("Cannot use --changed-since and --watch together")
+		state.fmt.Finish()
+		return errors.New("cannot use --changed-since and --watch together")
+	}
+
+	if changedSince != "" {
+		for _, arg := range args {
+			if arg == stdinPath {
+				state.fmt.PrintErr("Cannot use --changed-since when reading from stdin")
+				state.fmt.Finish()
+				return errors.New("cannot use --changed-since when reading from stdin")
+			}
+		}
+
+		state.changes, err = getChangeSet(changedSince)
+		if err != nil {
+			errText := fmt.Sprintf("could not get files changed since %s: %v", changedSince, err)
+			state.fmt.PrintErr(errText)
+			state.fmt.Finish()
+			return errors.New(errText)
+		}
+		state.changedLines = changedLines
+	}
+
+	if planPath != "" {
+		if watch || changedSince != "" {
+			state.fmt.PrintErr("Cannot use --plan with --watch or --changed-since")
+			state.fmt.Finish()
+			return errors.New("cannot use --plan with --watch or --changed-since")
+		}
+
+		return state.runPlanLint(planPath, args, writeBaselinePath, sarifPath)
+	}
 
 	// Get paths from arguments, if no arguments were given attempt to get files from current dir.
 	var paths []string
+	readStdin := false
 	if len(args) == 0 {
 		defaultPath, err := os.Getwd()
 		if err != nil {
@@ -149,24 +361,75 @@ func runLint(cmd *cobra.Command, args []string) error {
 
 		paths = []string{defaultPath}
 	} else {
-		paths = args
+		for _, arg := range args {
+			if arg == stdinPath {
+				readStdin = true
+				continue
+			}
+			paths = append(paths, arg)
+		}
 	}
 
-	files, err := state.getTerraformFiles(paths)
-	if err != nil {
-		return err
+	if readStdin && watch {
+		state.fmt.PrintErr("Cannot watch stdin for changes")
+		state.fmt.Finish()
+		return errors.New("cannot watch stdin for changes")
 	}
 
-	if len(files) == 0 {
+	files := []string{}
+	if len(paths) > 0 {
+		files, err = state.getTerraformFiles(paths)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(files) == 0 && !readStdin {
 		state.fmt.PrintErr("No terraform files found")
 		state.fmt.Finish()
 		return errors.New("no terraform files found")
 	}
 
+	if state.changes != nil {
+		changedFiles := []string{}
+		for _, file := range files {
+			if state.changes.hasFile(file) {
+				changedFiles = append(changedFiles, file)
+			}
+		}
+		files = changedFiles
+
+		if len(files) == 0 {
+			state.fmt.PrintSuccess(fmt.Sprintf("No terraform files changed since %s", changedSince))
+			state.fmt.Finish()
+			return nil
+		}
+	}
+
 	startTime := time.Now()
 	numFiles := 0   // how many files we've ran through
 	numErrors := 0  // how many errors we've found
 	numSkipped := 0 // how many files we've skipped
+	numKnown := 0   // how many errors we've ignored because they're in the baseline
+
+	results := map[string]*linter.Result{} // the results of each file linted, kept for watch mode.
+
+	if readStdin {
+		result, err := state.lintStdin(stdinFilename)
+		if err != nil {
+			state.fmt.PrintErr(fmt.Sprintf("Skipped stdin; could not lint: %v\n", err), polyfmt.Pretty)
+			state.fmt.PrintErr(map[string]interface{}{
+				"skipped_file": fmt.Sprintf("Skipped stdin; could not lint: %v\n", err),
+			}, polyfmt.JSON)
+			numSkipped++
+		} else {
+			known := state.applyBaseline(result)
+			state.printResult(result)
+			numErrors = numErrors + len(result.LintErrors)
+			numKnown = numKnown + known
+			numFiles++
+		}
+	}
 
 	for _, file := range files {
 		file, err := os.Open(file)
@@ -181,7 +444,7 @@ func runLint(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		errorsFound, err := state.lintFile(file)
+		result, err := state.lintFile(file)
 		if err != nil {
 			file.Close() // Close the file handle if we're not going to process it.
 			state.fmt.PrintErr(
@@ -193,7 +456,11 @@ func runLint(cmd *cobra.Command, args []string) error {
 			numSkipped++
 			continue
 		}
-		numErrors = numErrors + errorsFound
+		known := state.filterResult(result)
+		state.printResult(result)
+		results[file.Name()] = result
+		numErrors = numErrors + len(result.LintErrors)
+		numKnown = numKnown + known
 		numFiles++
 		file.Close() // don't defer things that are in loops
 	}
@@ -202,135 +469,175 @@ func runLint(cmd *cobra.Command, args []string) error {
 	durationSeconds := float64(duration) / float64(time.Second)
 	timePerFile := float64(duration) / float64(numFiles)
 
-	state.fmt.PrintSuccess(fmt.Sprintf("Found %d error(s) and skipped %d file(s)", numErrors, numSkipped))
-	state.fmt.PrintSuccess(fmt.Sprintf("Linted %d file(s) in %.2fs (avg %.2fms/file)",
-		numFiles, durationSeconds, timePerFile/float64(time.Millisecond)))
+	if state.baseline != nil {
+		state.fmt.PrintSuccess(fmt.Sprintf("Found %d new error(s), ignored %d baselined error(s) and skipped %d file(s)",
+			numErrors, numKnown, numSkipped))
+		if numFixed := state.baseline.Fixed(); numFixed > 0 {
+			state.fmt.PrintSuccess(fmt.Sprintf("%d baselined error(s) have since been fixed; "+
+				"run with --write-baseline to update the baseline", numFixed))
+		}
+	} else {
+		state.fmt.PrintSuccess(fmt.Sprintf("Found %d error(s) and skipped %d file(s)", numErrors, numSkipped))
+	}
+	state.fmt.PrintSuccess(fmt.Sprintf("Linted %d file(s) in %.2fs (avg %.2fms/file)%s",
+		numFiles, durationSeconds, timePerFile/float64(time.Millisecond), state.cacheStats()))
+
+	if state.writeBaseline != nil {
+		err = state.writeBaseline.Write(writeBaselinePath)
+		if err != nil {
+			errText := fmt.Sprintf("could not write baseline: %v", err)
+			state.fmt.PrintErr(errText)
+			state.fmt.Finish()
+			return errors.New(errText)
+		}
+
+		state.fmt.PrintSuccess(fmt.Sprintf("Wrote %d error(s) to baseline %s",
+			len(state.writeBaseline.Findings), writeBaselinePath))
+	}
+
+	if state.sarif != nil {
+		err = state.sarif.Write(sarifPath)
+		if err != nil {
+			errText := fmt.Sprintf("could not write SARIF file: %v", err)
+			state.fmt.PrintErr(errText)
+			state.fmt.Finish()
+			return errors.New(errText)
+		}
+
+		state.fmt.PrintSuccess(fmt.Sprintf("Wrote %d error(s) to SARIF file %s",
+			len(state.sarif.Runs[0].Results), sarifPath))
+	}
 	state.fmt.Finish()
 
+	if watch {
+		// The baseline and SARIF file were written from the initial run, so there's nothing left
+		// to record.
+		state.writeBaseline = nil
+		state.sarif = nil
+		return state.watch(paths, format, results)
+	}
+
 	return nil
 }
 
 // lintFile orchestrates the process of linting the given file.
-func (s *state) lintFile(file *os.File) (int, error) {
+func (s *state) lintFile(file *os.File) (*linter.Result, error) {
 
 	// Check we have enough memory to store file
 	err := checkAvailMemory(file)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	contents, err := ioutil.ReadAll(file)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	_, diags := hclparse.NewParser().ParseHCL(contents, file.Name())
-	if diags.HasErrors() {
-		return 0, diags
+	return s.linter.LintFile(file.Name(), contents)
+}
+
+// lintStdin lints a terraform file read from stdin. Since there is no actual file, lint errors
+// reference the given filename instead.
+func (s *state) lintStdin(filename string) (*linter.Result, error) {
+	contents, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
 	}
 
-	rulesets := s.cfg.Rulesets
-	errorsFound := 0
+	return s.linter.LintFile(filename, contents)
+}
 
-	// For each ruleset we need to run each one of the enabled rules against the given file.
-	for _, ruleset := range rulesets {
-		if !ruleset.Enabled {
-			continue
-		}
+// cacheStats returns a short description of how many rule runs were served from the cache, for
+// use in summary lines.
+func (s *state) cacheStats() string {
+	if s.linter.Cache == nil {
+		return ""
+	}
 
-		for _, rule := range ruleset.Rules {
-			if !rule.Enabled {
-				continue
-			}
+	hits, misses := s.linter.Cache.Stats()
+	if hits+misses == 0 {
+		return ""
+	}
 
-			s.fmt.Print(fmt.Sprintf("%q ruleset linting %q for rule %q",
-				strings.ToLower(ruleset.Name), filepath.Base(file.Name()), strings.ToLower(rule.Name)))
+	return fmt.Sprintf("; %d of %d rule run(s) cached (%.0f%% hit rate)",
+		hits, hits+misses, float64(hits)/float64(hits+misses)*100)
+}
 
-			numErrors, err := s.runRule(ruleset.Name, rule, file.Name(), contents)
-			if err != nil {
-				s.fmt.PrintErr(fmt.Sprintf("Rule failed %s; encountered an error while running: %v",
-					rule.Name, err))
-				continue
-			}
+// applyBaseline records the lint errors of a result in the baseline being written, if any, and
+// removes the ones already in the baseline being checked against. It returns how many lint errors
+// were removed.
+func (s *state) applyBaseline(result *linter.Result) (known int) {
+	if s.writeBaseline != nil {
+		s.writeBaseline.Add(result)
+	}
 
-			errorsFound = errorsFound + numErrors
+	if s.baseline == nil {
+		return 0
+	}
+
+	return s.baseline.Filter(result)
+}
+
+// filterResult applies the baseline to the lint errors of a file and then, with --changed-lines,
+// removes those not on a changed line. The baseline goes first so that it sees every lint error of
+// the file; otherwise baselined errors outside the diff would count as fixed or be left out of the
+// baseline being written. It returns how many lint errors the baseline removed.
+func (s *state) filterResult(result *linter.Result) (known int) {
+	known = s.applyBaseline(result)
+
+	if s.changedLines {
+		err := s.changes.filterChangedLines(result)
+		if err != nil {
+			s.fmt.PrintErr(fmt.Sprintf("Could not get changed lines of %s; reporting all errors: %v",
+				filepath.Base(result.Filepath), err))
 		}
 	}
 
-	return errorsFound, nil
+	return known
 }
 
-// runRule runs the rule plugin and returns the number of errors found.
-func (s *state) runRule(ruleset string, rule models.Rule, filepath string, rawHCLFile []byte) (int, error) {
-	tmpPluginName := "tfvetPlugin"
-
-	client := plugin.NewClient(&plugin.ClientConfig{
-		HandshakeConfig: tfvetPlugin.Handshake,
-		Plugins: map[string]plugin.Plugin{
-			tmpPluginName: &tfvetPlugin.TfvetRulePlugin{},
-		},
-		Cmd: exec.Command(appcfg.RulePath(ruleset, rule.ID)),
-		Logger: hclog.New(&hclog.LoggerOptions{
-			Output: ioutil.Discard,
-			Level:  0,
-			Name:   "plugin",
-		}),
-		Stderr:           nil,
-		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
-	})
-	defer client.Kill()
-
-	rpcClient, err := client.Client()
-	if err != nil {
-		return 0, fmt.Errorf("could not create rpc client: %w", err)
+// printResult prints the rule failures and lint errors found in a single file, and records them in
+// the SARIF file being written, if any.
+func (s *state) printResult(result *linter.Result) {
+	if s.sarif != nil {
+		s.sarif.Add(result)
 	}
 
-	raw, err := rpcClient.Dispense(tmpPluginName)
-	if err != nil {
-		return 0, fmt.Errorf("could not connect to rule plugin: %w", err)
+	for _, failure := range result.Failures {
+		s.fmt.PrintErr(fmt.Sprintf("Rule failed %s; encountered an error while running: %v",
+			failure.Rule.Name, failure.Err))
 	}
 
-	plugin, ok := raw.(tfvetPlugin.RuleDefinition)
-	if !ok {
-		return 0, fmt.Errorf("could not convert rule interface: %w", err)
+	for _, diagnostic := range result.Diagnostics {
+		s.printRuleDiagnostic(diagnostic)
 	}
 
-	response, err := plugin.ExecuteRule(&proto.ExecuteRuleRequest{
-		HclFile: rawHCLFile,
-	})
-	if err != nil {
-		return 0, fmt.Errorf("could not execute linting rule: %w", err)
+	for _, lintError := range result.LintErrors {
+		s.printLintError(lintError)
 	}
+}
 
-	ruleErrs := response.Errors
-	for _, ruleError := range ruleErrs {
-		line, _, err := utils.ReadLine(bytes.NewBuffer(rawHCLFile), int(ruleError.Location.Start.Line))
-		if err != nil {
-			return 0, fmt.Errorf("could not get line from file: %w", err)
-		}
+// printRuleDiagnostic prints a problem a rule ran into in both pretty and json formats.
+func (s *state) printRuleDiagnostic(diagnostic linter.RuleDiagnostic) {
+	s.fmt.PrintErr(formatRuleDiagnostic(diagnostic)+"\n", polyfmt.Pretty)
 
-		s.fmt.PrintErr(formatLintError(models.LintError{
-			Filepath: filepath,
-			Line:     line,
-			Ruleset:  ruleset,
-			Rule:     rule,
-			RuleErr:  *models.ProtoToRuleError(ruleError),
-		})+"\n", polyfmt.Pretty)
-
-		s.fmt.PrintErr(struct {
-			LintError models.LintError `json:"lint_error"`
-		}{
-			LintError: models.LintError{
-				Filepath: filepath,
-				Line:     line,
-				Ruleset:  ruleset,
-				Rule:     rule,
-				RuleErr:  *models.ProtoToRuleError(ruleError),
-			},
-		}, polyfmt.JSON)
-	}
-
-	return len(ruleErrs), nil
+	s.fmt.PrintErr(struct {
+		RuleDiagnostic linter.RuleDiagnostic `json:"rule_diagnostic"`
+	}{
+		RuleDiagnostic: diagnostic,
+	}, polyfmt.JSON)
+}
+
+// printLintError prints a single lint error in both pretty and json formats.
+func (s *state) printLintError(lintError models.LintError) {
+	s.fmt.PrintErr(formatLintError(lintError)+"\n", polyfmt.Pretty)
+
+	s.fmt.PrintErr(struct {
+		LintError models.LintError `json:"lint_error"`
+	}{
+		LintError: lintError,
+	}, polyfmt.JSON)
 }
 
 // checkAvailMemory compares the file size of a given file vs the available
@@ -352,5 +659,21 @@ func checkAvailMemory(file *os.File) error {
 }
 
 func init() {
+	cmdLint.Flags().Bool("watch", false, "keep running and re-lint files as they change")
+	cmdLint.Flags().String("stdin-filename", "<stdin>",
+		"file name to report lint errors against when reading from stdin")
+	cmdLint.Flags().String("baseline", "", "only report lint errors that aren't in the given baseline file")
+	cmdLint.Flags().Lookup("baseline").NoOptDefVal = baseline.DefaultPath
+	cmdLint.Flags().String("write-baseline", "", "record all lint errors found in the given baseline file")
+	cmdLint.Flags().String("sarif", "", "write the lint errors found to the given file in the SARIF format")
+	cmdLint.Flags().String("changed-since", "", "only lint files changed since the given git revision")
+	cmdLint.Flags().Bool("changed-lines", false,
+		"only report lint errors on lines changed since the revision given to --changed-since")
+	cmdLint.Flags().Bool("no-cache", false, "always run rules instead of using cached results")
+	cmdLint.Flags().String("plan", "", "lint the given terraform plan in JSON format instead of terraform files")
+	cmdLint.Flags().StringSlice("tags", nil, "only run rules with at least one of the given tags or categories")
+	cmdLint.Flags().StringSlice("exclude-category", nil,
+		"don't run rules in the given categories; accepted values are 'security', 'cost', 'style', 'correctness'")
+
 	RootCmd.AddCommand(cmdLint)
 }
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+// hunkHeader matches the header of a unified diff hunk and captures the start and length of the
+// hunk in the new version of the file. The length is omitted by git when it is 1.
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// lineRange is an inclusive range of lines.
+type lineRange struct {
+	start int
+	end   int
+}
+
+// changeSet is the set of files changed since a git revision.
+type changeSet struct {
+	base  string          // the commit files are compared against.
+	root  string          // the root of the git repository.
+	files map[string]bool // absolute paths of all changed files.
+	// untracked files are new, so all of their lines count as changed.
+	untracked map[string]bool
+}
+
+// getChangeSet returns the files changed in the git repository of the current directory since it
+// diverged from the given revision. Comparing against the merge base, instead of the revision
+// itself, means changes made to the revision since don't count, which is what pull request checks
+// want. Uncommitted and untracked files are included.
+func getChangeSet(revision string) (*changeSet, error) {
+	root, err := runGit("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = resolvePath(strings.TrimSpace(root))
+
+	base, err := runGit(root, "merge-base", revision, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	base = strings.TrimSpace(base)
+
+	changes := &changeSet{
+		base:      base,
+		root:      root,
+		files:     map[string]bool{},
+		untracked: map[string]bool{},
+	}
+
+	// Deleted files can't be linted so they are filtered out.
+	changed, err := runGit(root, "diff", "--name-only", "-z", "--no-renames", "--diff-filter=d", base)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range strings.Split(changed, "\x00") {
+		if name != "" {
+			changes.files[filepath.Join(root, name)] = true
+		}
+	}
+
+	untracked, err := runGit(root, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range strings.Split(untracked, "\x00") {
+		if name != "" {
+			changes.files[filepath.Join(root, name)] = true
+			changes.untracked[filepath.Join(root, name)] = true
+		}
+	}
+
+	return changes, nil
+}
+
+// hasFile returns whether the file at the given absolute path was changed.
+func (c *changeSet) hasFile(path string) bool {
+	return c.files[resolvePath(path)]
+}
+
+// changedLines returns the lines of the given file that were added or modified. A nil result means
+// the whole file is new.
+func (c *changeSet) changedLines(path string) ([]lineRange, error) {
+	path = resolvePath(path)
+
+	if c.untracked[path] {
+		return nil, nil
+	}
+
+	diff, err := runGit(c.root, "diff", "-U0", "--no-color", "--no-ext-diff", "--no-renames", c.base, "--", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHunks(diff)
+}
+
+// parseHunks returns the lines added or modified by a unified diff of a single file without
+// context lines.
+func parseHunks(diff string) ([]lineRange, error) {
+	ranges := []lineRange{}
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	for scanner.Scan() {
+		match := hunkHeader.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+
+		start, _ := strconv.Atoi(match[1])
+		length := 1
+		if match[2] != "" {
+			length, _ = strconv.Atoi(match[2])
+		}
+
+		// Hunks that only remove lines don't leave anything to lint.
+		if length == 0 {
+			continue
+		}
+
+		ranges = append(ranges, lineRange{start: start, end: start + length - 1})
+	}
+
+	return ranges, scanner.Err()
+}
+
+// filterChangedLines removes lint errors that don't touch any changed line of the file. The whole
+// file is still linted beforehand so that rules have the full context of the file available.
+// Lint errors without a location apply to the whole file, so they're kept as the file changed.
+func (c *changeSet) filterChangedLines(result *linter.Result) error {
+	path, err := filepath.Abs(result.Filepath)
+	if err != nil {
+		return err
+	}
+
+	ranges, err := c.changedLines(path)
+	if err != nil {
+		return err
+	}
+
+	if ranges == nil {
+		return nil
+	}
+
+	lintErrors := []models.LintError{}
+	for _, lintError := range result.LintErrors {
+		if lintError.RuleErr.Location.Start.Line == 0 {
+			lintErrors = append(lintErrors, lintError)
+			continue
+		}
+
+		start := int(lintError.RuleErr.Location.Start.Line)
+		end := int(lintError.RuleErr.Location.End.Line)
+		if end < start {
+			end = start
+		}
+
+		for _, changed := range ranges {
+			if start <= changed.end && end >= changed.start {
+				lintErrors = append(lintErrors, lintError)
+				break
+			}
+		}
+	}
+
+	result.LintErrors = lintErrors
+	return nil
+}
+
+// resolvePath resolves any symlinks in the given path so that paths reported by git can be compared
+// to the ones given by the user.
+func resolvePath(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+
+	return resolved
+}
+
+// runGit runs a git command in the given directory and returns its output.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+
+		return "", fmt.Errorf("could not run git: %w", err)
+	}
+
+	return string(output), nil
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/clintjedwards/polyfmt"
+	"github.com/clintjedwards/tfvet/v2/internal/baseline"
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+func TestParseHunks(t *testing.T) {
+	tests := map[string]struct {
+		diff   string
+		ranges []lineRange
+	}{
+		"modified line": {
+			diff:   "@@ -2 +2 @@\n-a\n+b\n",
+			ranges: []lineRange{{start: 2, end: 2}},
+		},
+		"added lines": {
+			diff:   "@@ -3,0 +4,2 @@\n+a\n+b\n",
+			ranges: []lineRange{{start: 4, end: 5}},
+		},
+		"pure deletion": {
+			diff:   "@@ -4,2 +3,0 @@\n-a\n-b\n",
+			ranges: []lineRange{},
+		},
+		"new file": {
+			diff:   "--- /dev/null\n+++ b/main.tf\n@@ -0,0 +1,3 @@ resource\n+a\n+b\n+c\n",
+			ranges: []lineRange{{start: 1, end: 3}},
+		},
+		"several hunks": {
+			diff:   "@@ -1,2 +1 @@\n-a\n-b\n+c\n@@ -10 +9,0 @@\n-d\n@@ -20,0 +20,3 @@\n+e\n+f\n+g\n",
+			ranges: []lineRange{{start: 1, end: 1}, {start: 20, end: 22}},
+		},
+	}
+
+	for name, test := range tests {
+		ranges, err := parseHunks(test.diff)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if !reflect.DeepEqual(ranges, test.ranges) {
+			t.Errorf("%s: expected %v; got %v", name, test.ranges, ranges)
+		}
+	}
+}
+
+// lintErrorsOn returns a result for the file with a lint error spanning each of the given ranges.
+func lintErrorsOn(path string, spans ...lineRange) *linter.Result {
+	result := &linter.Result{Filepath: path}
+	for _, span := range spans {
+		result.LintErrors = append(result.LintErrors, models.LintError{
+			Filepath: path,
+			RuleErr: models.RuleError{Location: models.Range{
+				Start: models.Position{Line: uint32(span.start)},
+				End:   models.Position{Line: uint32(span.end)},
+			}},
+		})
+	}
+
+	return result
+}
+
+// changedRepo creates a git repository with changes since the branch "base" and makes it the
+// working directory for the rest of the test. Lines 4 and 5 of main.tf are new, deleted.tf only
+// loses a line and old.tf is renamed to new.tf.
+func changedRepo(t *testing.T) (string, *changeSet) {
+	t.Helper()
+
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+
+		_, err := runGit(root, append([]string{"-c", "user.name=tfvet", "-c", "user.email=tfvet@example.com"}, args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+
+		err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("main.tf", "line1\nline2\nline3\nline4\nline5\nline6\n")
+	write("deleted.tf", "line1\nline2\nline3\n")
+	write("old.tf", "line1\nline2\n")
+	git("add", "-A")
+	git("commit", "-qm", "base")
+	git("branch", "base")
+
+	write("main.tf", "line1\nline2\nline3\nnew4\nnew5\nline4\nline5\nline6\n")
+	write("deleted.tf", "line1\nline3\n")
+	git("mv", "old.tf", "new.tf")
+	git("commit", "-qam", "change")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	changes, err := getChangeSet("base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return root, changes
+}
+
+func TestFilterChangedLines(t *testing.T) {
+	root, changes := changedRepo(t)
+
+	if !changes.hasFile(filepath.Join(root, "new.tf")) || changes.hasFile(filepath.Join(root, "old.tf")) {
+		t.Fatalf("expected the renamed file to be changed under its new name; got %v", changes.files)
+	}
+
+	tests := map[string]struct {
+		result *linter.Result
+		kept   int
+	}{
+		// Errors spanning the edge of a hunk touch a changed line and are kept.
+		"added lines": {
+			result: lintErrorsOn(filepath.Join(root, "main.tf"),
+				lineRange{1, 1}, lineRange{2, 4}, lineRange{4, 4}, lineRange{5, 7}, lineRange{6, 8}),
+			kept: 3,
+		},
+		"pure deletion": {
+			result: lintErrorsOn(filepath.Join(root, "deleted.tf"), lineRange{1, 1}, lineRange{2, 2}),
+			kept:   0,
+		},
+		"renamed file": {
+			result: lintErrorsOn(filepath.Join(root, "new.tf"), lineRange{1, 1}, lineRange{2, 2}),
+			kept:   2,
+		},
+		// Errors without an end line are treated as a single line.
+		"no end line": {
+			result: lintErrorsOn(filepath.Join(root, "main.tf"), lineRange{5, 0}, lineRange{3, 0}),
+			kept:   1,
+		},
+		// Errors without a location apply to the whole file, which changed.
+		"no location": {
+			result: lintErrorsOn(filepath.Join(root, "main.tf"), lineRange{0, 0}, lineRange{1, 1}),
+			kept:   1,
+		},
+		"no location in pure deletion": {
+			result: lintErrorsOn(filepath.Join(root, "deleted.tf"), lineRange{0, 0}),
+			kept:   1,
+		},
+	}
+
+	for name, test := range tests {
+		err := changes.filterChangedLines(test.result)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if len(test.result.LintErrors) != test.kept {
+			t.Errorf("%s: expected %d lint error(s) to be kept; got %d", name, test.kept, len(test.result.LintErrors))
+		}
+	}
+}
+
+func TestFilterResult(t *testing.T) {
+	root, changes := changedRepo(t)
+
+	// Lint errors on the unchanged line 1 and the new line 4 are in the baseline.
+	lintErrors := func() *linter.Result {
+		result := lintErrorsOn(filepath.Join(root, "main.tf"),
+			lineRange{1, 1}, lineRange{4, 4}, lineRange{5, 5}, lineRange{7, 7})
+		for index := range result.LintErrors {
+			result.LintErrors[index].RuleErr.Suggestion = fmt.Sprintf("error %d", index)
+		}
+		return result
+	}
+
+	previous := baseline.New()
+	recorded := lintErrors()
+	recorded.LintErrors = recorded.LintErrors[:2]
+	previous.Add(recorded)
+
+	silent, err := polyfmt.NewFormatter(polyfmt.Silent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &state{
+		fmt:           silent,
+		baseline:      previous,
+		writeBaseline: baseline.New(),
+		changes:       changes,
+		changedLines:  true,
+	}
+
+	result := lintErrors()
+	known := s.filterResult(result)
+
+	// The baseline sees every lint error, so the one on an unchanged line isn't fixed, while only
+	// the new error on a changed line is reported.
+	if known != 2 || previous.Fixed() != 0 {
+		t.Fatalf("expected 2 known and no fixed errors; got %d known and %d fixed", known, previous.Fixed())
+	}
+	if len(result.LintErrors) != 1 || result.LintErrors[0].RuleErr.Location.Start.Line != 5 {
+		t.Fatalf("unexpected remaining lint errors %+v", result.LintErrors)
+	}
+	if len(s.writeBaseline.Findings) != 4 {
+		t.Fatalf("expected all lint errors to be written to the baseline; got %d", len(s.writeBaseline.Findings))
+	}
+}
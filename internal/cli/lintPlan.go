@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runPlanLint lints a terraform plan in JSON format. The configuration the plan was created from
+// is looked up in the directory given in args, or the current directory if none is given.
+func (s *state) runPlanLint(planPath string, args []string, writeBaselinePath, sarifPath string) error {
+	if len(args) > 1 {
+		s.fmt.PrintErr("--plan accepts at most one path; the directory of the plan's configuration")
+		s.fmt.Finish()
+		return errors.New("--plan accepts at most one path")
+	}
+
+	configDir, err := os.Getwd()
+	if err != nil {
+		s.fmt.PrintErr(fmt.Sprintf("could not get current directory: %v", err))
+		s.fmt.Finish()
+		return err
+	}
+
+	if len(args) == 1 {
+		configDir, err = filepath.Abs(args[0])
+		if err != nil {
+			errText := fmt.Sprintf("could not parse path %s", args[0])
+			s.fmt.PrintErr(errText)
+			s.fmt.Finish()
+			return errors.New(errText)
+		}
+	}
+
+	contents, err := ioutil.ReadFile(planPath)
+	if err != nil {
+		errText := fmt.Sprintf("could not read plan: %v", err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	startTime := time.Now()
+
+	results, err := s.linter.LintPlan(planPath, contents, configDir)
+	if err != nil {
+		errText := fmt.Sprintf("could not lint plan %s: %v", planPath, err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	numErrors := 0 // how many errors we've found
+	numKnown := 0  // how many errors we've ignored because they're in the baseline
+
+	for _, result := range results {
+		known := s.applyBaseline(result)
+		s.printResult(result)
+		numErrors = numErrors + len(result.LintErrors)
+		numKnown = numKnown + known
+	}
+
+	durationSeconds := float64(time.Since(startTime)) / float64(time.Second)
+
+	if s.baseline != nil {
+		s.fmt.PrintSuccess(fmt.Sprintf("Found %d new error(s) and ignored %d baselined error(s)",
+			numErrors, numKnown))
+		if numFixed := s.baseline.Fixed(); numFixed > 0 {
+			s.fmt.PrintSuccess(fmt.Sprintf("%d baselined error(s) have since been fixed; "+
+				"run with --write-baseline to update the baseline", numFixed))
+		}
+	} else {
+		s.fmt.PrintSuccess(fmt.Sprintf("Found %d error(s)", numErrors))
+	}
+	s.fmt.PrintSuccess(fmt.Sprintf("Linted plan %s in %.2fs%s", planPath, durationSeconds, s.cacheStats()))
+
+	if s.writeBaseline != nil {
+		err = s.writeBaseline.Write(writeBaselinePath)
+		if err != nil {
+			errText := fmt.Sprintf("could not write baseline: %v", err)
+			s.fmt.PrintErr(errText)
+			s.fmt.Finish()
+			return errors.New(errText)
+		}
+
+		s.fmt.PrintSuccess(fmt.Sprintf("Wrote %d error(s) to baseline %s",
+			len(s.writeBaseline.Findings), writeBaselinePath))
+	}
+
+	if s.sarif != nil {
+		err = s.sarif.Write(sarifPath)
+		if err != nil {
+			errText := fmt.Sprintf("could not write SARIF file: %v", err)
+			s.fmt.PrintErr(errText)
+			s.fmt.Finish()
+			return errors.New(errText)
+		}
+
+		s.fmt.PrintSuccess(fmt.Sprintf("Wrote %d error(s) to SARIF file %s",
+			len(s.sarif.Runs[0].Results), sarifPath))
+	}
+	s.fmt.Finish()
+
+	return nil
+}
@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/clintjedwards/polyfmt"
+	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/fsnotify/fsnotify"
+	"github.com/mitchellh/go-homedir"
+)
+
+// watchDebounce is how long to wait after the last change before re-linting. Editors commonly
+// write a file in several steps and save many files at once; this makes sure all of that only
+// results in a single run.
+const watchDebounce = 200 * time.Millisecond
+
+// clearScreen moves the cursor to the top left corner of the terminal and clears it.
+const clearScreen = "\033[H\033[2J"
+
+// watch re-lints the terraform files matched by the given paths whenever they change and redraws a
+// summary of all lint errors found. It runs until interrupted. Results are the results of the
+// initial run, keyed by file path.
+func (s *state) watch(paths []string, format string, results map[string]*linter.Result) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	defer watcher.Close()
+
+	// We watch directories instead of files, as editors often save by replacing the file, which ends
+	// a watch on the file itself. The config directory is watched for the same reason since the
+	// config file is also replaced on every write.
+	dirs := map[string]bool{
+		appcfg.ConfigPath(): true,
+	}
+
+	for _, path := range paths {
+		path, err := homedir.Expand(path)
+		if err != nil {
+			return err
+		}
+
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+
+		dirs[filepath.Dir(path)] = true
+	}
+
+	for dir := range dirs {
+		err := watcher.Add(dir)
+		if err != nil {
+			err = fmt.Errorf("could not watch %s: %w", dir, err)
+			log.Print(err)
+			return err
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	changed := map[string]bool{}
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case <-interrupt:
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if event.Name != appcfg.ConfigFilePath() && !isTerraformFile(event.Name) {
+				continue
+			}
+
+			changed[event.Name] = true
+			debounce = time.After(watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			log.Printf("error watching files: %v", err)
+
+		case <-debounce:
+			debounce = nil
+
+			s.relint(paths, format, changed, results)
+			changed = map[string]bool{}
+		}
+	}
+}
+
+// relint lints the changed files again, reloading the enabled rules first if the config changed,
+// and redraws the summary.
+func (s *state) relint(paths []string, format string, changed map[string]bool,
+	results map[string]*linter.Result) {
+
+	redraw := polyfmt.Mode(format) == polyfmt.Pretty && isTTY()
+	if redraw {
+		fmt.Print(clearScreen)
+	}
+
+	clifmt, err := polyfmt.NewFormatter(polyfmt.Mode(format))
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	s.fmt = clifmt
+
+	startTime := time.Now()
+
+	if changed[appcfg.ConfigFilePath()] {
+		cfg, err := appcfg.GetConfig()
+		if err != nil {
+			s.fmt.PrintErr(fmt.Sprintf("error reading config file %q: %v; keeping previously enabled rules",
+				appcfg.ConfigFilePath(), err))
+		} else {
+			s.cfg = cfg
+			s.linter.SetRulesets(cfg.Rulesets)
+
+			// The enabled rules might have changed so every file has to be linted again.
+			for file := range results {
+				changed[file] = true
+			}
+		}
+	}
+
+	files, err := s.getTerraformFiles(paths)
+	if err != nil {
+		return
+	}
+
+	// Forget about files that have since been removed.
+	current := map[string]bool{}
+	for _, file := range files {
+		current[file] = true
+	}
+	for file := range results {
+		if !current[file] {
+			delete(results, file)
+		}
+	}
+
+	linted := []string{}
+	numSkipped := 0
+
+	for _, file := range files {
+		if !changed[file] {
+			continue
+		}
+
+		result, err := s.lintPath(file)
+		if err != nil {
+			delete(results, file)
+			s.fmt.PrintErr(fmt.Sprintf("Skipped file %s; %v", filepath.Base(file), err), polyfmt.Pretty)
+			s.fmt.PrintErr(map[string]interface{}{
+				"skipped_file": fmt.Sprintf("Skipped file %s; %v", filepath.Base(file), err),
+			}, polyfmt.JSON)
+			numSkipped++
+			continue
+		}
+
+		s.applyBaseline(result)
+		results[file] = result
+		linted = append(linted, file)
+
+		for _, failure := range result.Failures {
+			s.fmt.PrintErr(fmt.Sprintf("Rule failed %s; encountered an error while running: %v",
+				failure.Rule.Name, failure.Err))
+		}
+
+		for _, diagnostic := range result.Diagnostics {
+			s.printRuleDiagnostic(diagnostic)
+		}
+
+		// Files that haven't changed keep the errors previously printed for them, so in json
+		// mode we only print what's new. The pretty summary below is redrawn in full instead.
+		for _, lintError := range result.LintErrors {
+			s.fmt.PrintErr(struct {
+				LintError models.LintError `json:"lint_error"`
+			}{
+				LintError: lintError,
+			}, polyfmt.JSON)
+		}
+	}
+
+	s.printWatchSummary(results)
+
+	numErrors := 0
+	numFilesWithErrors := 0
+	for _, result := range results {
+		numErrors += len(result.LintErrors)
+		if len(result.LintErrors) > 0 {
+			numFilesWithErrors++
+		}
+	}
+
+	s.fmt.PrintSuccess(fmt.Sprintf("Found %d error(s) in %d of %d file(s)",
+		numErrors, numFilesWithErrors, len(results)))
+	s.fmt.PrintSuccess(fmt.Sprintf("Re-linted %d file(s) and skipped %d file(s) in %.2fs at %s; watching for changes",
+		len(linted), numSkipped, time.Since(startTime).Seconds(), time.Now().Format("15:04:05")))
+	s.fmt.Finish()
+}
+
+// printWatchSummary prints a compact, one line per error, listing of all lint errors currently
+// known across all files.
+func (s *state) printWatchSummary(results map[string]*linter.Result) {
+	files := []string{}
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	cwd, _ := os.Getwd()
+
+	for _, file := range files {
+		displayPath := file
+		if relPath, err := filepath.Rel(cwd, file); err == nil && !strings.HasPrefix(relPath, "..") {
+			displayPath = relPath
+		}
+
+		for _, lintError := range results[file].LintErrors {
+			// Lint errors without a location apply to the file as a whole.
+			location := displayPath
+			if start := lintError.RuleErr.Location.Start; start.Line > 0 {
+				location = fmt.Sprintf("%s:%d:%d", displayPath, start.Line, start.Column)
+			}
+
+			s.fmt.PrintErr(fmt.Sprintf("%s: [%s] %s", location, lintError.Rule.ID, lintError.Rule.Short),
+				polyfmt.Pretty)
+		}
+	}
+}
+
+// lintPath lints the file at the given path.
+func (s *state) lintPath(path string) (*linter.Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open: %w", err)
+	}
+	defer file.Close()
+
+	return s.lintFile(file)
+}
+
+// isTTY determines if stdout is an interactive terminal.
+func isTTY() bool {
+	fileInfo, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+
+	return (fileInfo.Mode() & os.ModeCharDevice) != 0
+}
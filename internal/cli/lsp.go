@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	"github.com/clintjedwards/tfvet/v2/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+// cmdLsp is a subcommand that runs tfvet as a language server
+var cmdLsp = &cobra.Command{
+	Use:   "lsp",
+	Short: "Runs the tfvet language server",
+	Long: `Runs a language server speaking the Language Server Protocol over stdio.
+
+Editors can use it to show lint errors as diagnostics while files are edited, apply rule
+remediations as quick fixes and show rule documentation on hover.
+
+All enabled rules are run. Rule plugins are started once and kept running for as long as the
+editor keeps the server open.
+`,
+	RunE:    runLsp,
+	Example: `$ tfvet lsp`,
+}
+
+func runLsp(cmd *cobra.Command, _ []string) error {
+	// Stdout is reserved for protocol messages so errors can only be reported on stderr.
+	logger := log.New(os.Stderr, "tfvet lsp: ", 0)
+
+	cfg, err := appcfg.GetConfig()
+	if err != nil {
+		err = fmt.Errorf("error reading config file %q: %w", appcfg.ConfigFilePath(), err)
+		logger.Print(err)
+		return err
+	}
+
+	ruleLinter := linter.New(cfg.Rulesets)
+	defer ruleLinter.Close()
+
+	ruleLinter.Logger, err = newRuleLogger(cmd)
+	if err != nil {
+		logger.Print(err)
+		return err
+	}
+
+	err = lsp.NewServer(ruleLinter, appVersion).Run(os.Stdin, os.Stdout)
+	if err != nil {
+		logger.Print(err)
+		return err
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(cmdLsp)
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
 	"github.com/clintjedwards/tfvet/v2/internal/cli/rule"
 	"github.com/clintjedwards/tfvet/v2/internal/cli/ruleset"
+	"github.com/clintjedwards/tfvet/v2/internal/config"
+	tfvetPlugin "github.com/clintjedwards/tfvet/v2/internal/plugin"
 	"github.com/clintjedwards/tfvet/v2/internal/utils"
+	"github.com/hashicorp/go-hclog"
 	"github.com/spf13/cobra"
 )
 
@@ -37,11 +40,30 @@ var RootCmd = &cobra.Command{
 
 func init() {
 	RootCmd.SetVersionTemplate(humanizeVersion(appVersion))
+	rule.Version = appVersion
 	RootCmd.AddCommand(ruleset.CmdRuleset)
 	RootCmd.AddCommand(rule.CmdRule)
 
 	RootCmd.PersistentFlags().StringP("format", "f", "pretty",
 		"output format; accepted values are 'pretty', 'json', 'silent'")
+	RootCmd.PersistentFlags().String("log-level", "",
+		"level of rule logs written to stderr; accepted values are 'trace', 'debug', 'info', 'warn',"+
+			" 'error', 'off'. Defaults to the value of TFVET_LOG or 'off'")
+}
+
+// newRuleLogger returns the logger rules log to, at the level set by the log-level flag or the
+// TFVET_LOG environment variable.
+func newRuleLogger(cmd *cobra.Command) (hclog.Logger, error) {
+	level, _ := cmd.Flags().GetString("log-level")
+	if level == "" {
+		cfg, err := config.FromEnv()
+		if err != nil {
+			return nil, err
+		}
+		level = cfg.Log
+	}
+
+	return tfvetPlugin.NewLogger(level)
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -0,0 +1,94 @@
+package rule
+
+import (
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ANSI escape codes used to highlight HCL.
+const (
+	colorReset   = "\033[0m"
+	colorComment = "\033[90m" // gray
+	colorString  = "\033[32m" // green
+	colorLiteral = "\033[35m" // magenta; numbers, booleans and null.
+	colorBlock   = "\033[1;34m"
+	colorAttr    = "\033[36m" // cyan
+)
+
+// useColor determines if output may be colored: stdout has to be an interactive terminal and the
+// user must not have opted out through NO_COLOR (https://no-color.org).
+func useColor() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	fileInfo, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+
+	return (fileInfo.Mode() & os.ModeCharDevice) != 0
+}
+
+// highlightHCL returns the given HCL source with ANSI colors applied to block types, attribute
+// names, strings, literals and comments. Source that can't be lexed is highlighted as far as
+// possible; whitespace and anything the lexer skips is kept as is.
+func highlightHCL(src string) string {
+	tokens, _ := hclsyntax.LexConfig([]byte(src), "example.tf", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+
+	var highlighted strings.Builder
+	last := 0
+	lineStart := true
+
+	for index, token := range tokens {
+		start, end := token.Range.Start.Byte, token.Range.End.Byte
+		if start < last || end > len(src) {
+			continue
+		}
+
+		highlighted.WriteString(src[last:start])
+		last = end
+
+		color := ""
+		switch token.Type {
+		case hclsyntax.TokenComment:
+			color = colorComment
+		case hclsyntax.TokenOQuote, hclsyntax.TokenCQuote, hclsyntax.TokenQuotedLit,
+			hclsyntax.TokenOHeredoc, hclsyntax.TokenCHeredoc, hclsyntax.TokenStringLit:
+			color = colorString
+		case hclsyntax.TokenNumberLit:
+			color = colorLiteral
+		case hclsyntax.TokenIdent:
+			next := hclsyntax.TokenEOF
+			if index+1 < len(tokens) {
+				next = tokens[index+1].Type
+			}
+
+			switch name := string(token.Bytes); {
+			case name == "true" || name == "false" || name == "null":
+				color = colorLiteral
+			case lineStart && next == hclsyntax.TokenEqual:
+				color = colorAttr
+			case lineStart:
+				color = colorBlock
+			}
+		}
+
+		if color == "" {
+			highlighted.WriteString(src[start:end])
+		} else {
+			highlighted.WriteString(color + src[start:end] + colorReset)
+		}
+
+		// Line comments include the newline that ends them.
+		lineStart = token.Type == hclsyntax.TokenNewline ||
+			(token.Type == hclsyntax.TokenComment && strings.HasSuffix(string(token.Bytes), "\n"))
+	}
+
+	highlighted.WriteString(src[last:])
+
+	return highlighted.String()
+}
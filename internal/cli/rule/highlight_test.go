@@ -0,0 +1,43 @@
+package rule
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHighlightHCL(t *testing.T) {
+	src := `# logs bucket
+resource "aws_s3_bucket" "logs" {
+  versioning = true
+  count      = 2
+  tags       = { team = "platform" }
+}
+`
+
+	highlighted := highlightHCL(src)
+
+	for _, want := range []string{
+		colorComment + "# logs bucket\n" + colorReset,
+		colorBlock + "resource" + colorReset,
+		colorString + "aws_s3_bucket" + colorReset,
+		colorAttr + "versioning" + colorReset,
+		colorLiteral + "true" + colorReset,
+		colorLiteral + "2" + colorReset,
+	} {
+		if !strings.Contains(highlighted, want) {
+			t.Errorf("expected %q in the highlighted source; got %q", want, highlighted)
+		}
+	}
+
+	ansi := regexp.MustCompile("\033\\[[0-9;]*m")
+	if plain := ansi.ReplaceAllString(highlighted, ""); plain != src {
+		t.Errorf("expected highlighting to keep the source as is; got %q", plain)
+	}
+
+	// Source that doesn't lex is still returned in full.
+	broken := `resource "unterminated`
+	if plain := ansi.ReplaceAllString(highlightHCL(broken), ""); plain != broken {
+		t.Errorf("expected invalid source to be kept as is; got %q", plain)
+	}
+}
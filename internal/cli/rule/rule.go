@@ -27,8 +27,10 @@ type state struct {
 	cfg *appcfg.Appcfg
 }
 
-// newState returns a new initialized state object
-func newState(initialFmtMsg, format string) (*state, error) {
+// newState returns a new initialized state object.
+// If lock is set the config is locked against changes from other tfvet processes; callers must
+// call state.cfg.Unlock() once they are done.
+func newState(initialFmtMsg, format string, lock bool) (*state, error) {
 	clifmt, err := polyfmt.NewFormatter(polyfmt.Mode(format))
 	if err != nil {
 		log.Fatal(err)
@@ -37,7 +39,12 @@ func newState(initialFmtMsg, format string) (*state, error) {
 
 	clifmt.Print(initialFmtMsg, polyfmt.Pretty)
 
-	cfg, err := appcfg.GetConfig()
+	getConfig := appcfg.GetConfig
+	if lock {
+		getConfig = appcfg.GetConfigLocked
+	}
+
+	cfg, err := getConfig()
 	if err != nil {
 		errText := fmt.Sprintf("error reading config file %q: %v", appcfg.ConfigFilePath(), err)
 		clifmt.PrintErr(errText)
@@ -1,19 +1,32 @@
 package rule
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"html/template"
+	"go/format"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
+	"unicode"
 
+	"github.com/Masterminds/semver"
 	"github.com/clintjedwards/tfvet/v2/internal/utils"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
 	"github.com/spf13/cobra"
 )
 
-// cmdRuleCreate creates a skeleton ruleset
+// Version is the version of the running tfvet; generated rules pin the sdk to it. It is set by
+// the cli package.
+var Version string
+
+// cmdRuleCreate creates a skeleton rule
 var cmdRuleCreate = &cobra.Command{
 	Use:   "create <name>",
 	Short: "Create a new rule",
@@ -22,33 +35,108 @@ var cmdRuleCreate = &cobra.Command{
 Navigate to the ruleset folder in which you mean to create the rule. From there, simply run this command
 to create all files and folders required for a tfvet rule.
 
-The rule name should short, alphanumeric, and have no spaces. It will be used as the directory name
-and hashed to provide the user a quick way to target said specific rule.
+The rule name should short, alphanumeric, and have no spaces. It will be used as the directory name.
+Rules should declare a short, stable ID (like AWS001) in main.go; users refer to the rule by this ID.
+The generated rule declares a placeholder ID made from its name, like BUCKETNAMES001 for bucket_names.
+If no ID is declared the directory name is hashed to provide one, which changes if the directory is renamed.
+
+The rule is generated from a template, each a working rule to adapt:
+` + templateDescriptions() + `
+Along with main.go, a test (run with "go test") and the testdata it lints are created. Unless the
+ruleset already has a go.mod file, the rule gets its own, which requires the sdk of the running version
+of tfvet. Once created the rule is compiled to make sure it is ready to be changed.
 `,
-	Example: `$ tfvet rule create example_rule_name`,
-	RunE:    runCreate,
-	Args:    cobra.ExactArgs(1),
+	Example: `$ tfvet rule create example_rule_name
+$ tfvet rule create bucket_names --template naming`,
+	RunE: runCreate,
+	Args: cobra.ExactArgs(1),
 }
 
 func init() {
+	cmdRuleCreate.Flags().StringP("template", "t", defaultRuleTemplate,
+		"the kind of rule to generate; accepted values are "+strings.Join(templateNames(), ", "))
+
 	CmdRule.AddCommand(cmdRuleCreate)
 }
 
-func runCreate(_ *cobra.Command, args []string) error {
+func runCreate(cmd *cobra.Command, args []string) error {
 	name := strings.ToLower(args[0])
 
-	err := validateName(name)
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	templateName, err := cmd.Flags().GetString("template")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	state, err := newState("Creating rule", format, false)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
 
-	err = createRuleDir(name)
+	tmpl, ok := ruleTemplates[templateName]
+	if !ok {
+		errText := fmt.Sprintf("unknown template %q; accepted values are %s",
+			templateName, strings.Join(templateNames(), ", "))
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	err = validateName(name)
+	if err != nil {
+		state.fmt.PrintErr(err.Error())
+		state.fmt.Finish()
+		return err
+	}
+
+	currentDir, err := os.Getwd()
 	if err != nil {
-		log.Println(err)
+		state.fmt.PrintErr(err.Error())
+		state.fmt.Finish()
 		return err
 	}
 
+	//TODO(clintjedwards): Take this from the appcfg package and stop declaring it everywhere
+	rulesDirName := "rules"
+	ruleDirPath := filepath.Join(currentDir, rulesDirName, name)
+
+	if _, err := os.Stat(ruleDirPath); err == nil {
+		errText := fmt.Sprintf("could not create rule: %s already exists", ruleDirPath)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	// A go.mod at the root of the ruleset already provides the sdk to all of its rules.
+	createGoMod := true
+	if _, err := os.Stat(filepath.Join(currentDir, "go.mod")); err == nil {
+		createGoMod = false
+	}
+
+	err = createRuleDir(ruleDirPath, name, tmpl, createGoMod)
+	if err != nil {
+		errText := fmt.Sprintf("could not create rule: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	state.fmt.Print("Compiling rule")
+
+	output, err := compileRule(ruleDirPath, createGoMod)
+	if err != nil {
+		errText := fmt.Sprintf("rule created at %s, but it does not compile: %v\n%s", ruleDirPath, err, output)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	state.fmt.PrintSuccess(fmt.Sprintf("Created rule %s from template %s", name, templateName))
+	state.fmt.Finish()
 	return nil
 }
 
@@ -65,142 +153,130 @@ func validateName(name string) error {
 	return nil
 }
 
-func createRuleDir(name string) error {
-	currentDir, err := os.Getwd()
+// createRuleDir writes the files of a new rule, generated from the given template, into
+// ruleDirPath.
+func createRuleDir(ruleDirPath, name string, tmpl ruleTemplate, createGoMod bool) error {
+	err := utils.CreateDir(filepath.Join(ruleDirPath, "testdata"))
 	if err != nil {
 		return err
 	}
 
-	//TODO(clintjedwards): Take this from the appcfg package and stop declaring it everywhere
-	rulesDirName := "rules"
-	ruleDirPath := fmt.Sprintf("%s/%s/%s", currentDir, rulesDirName, name)
-
-	err = utils.CreateDir(ruleDirPath)
-	if err != nil {
-		return err
+	data := struct {
+		ID      string
+		Name    string
+		Version string
+	}{
+		ID:      placeholderID(name),
+		Name:    name,
+		Version: sdkVersion(),
 	}
 
-	err = createMainFile(name)
-	if err != nil {
-		return err
+	files := map[string]string{
+		"main.go":      tmpl.main,
+		"main_test.go": ruleTestFile,
+	}
+	if createGoMod {
+		files["go.mod"] = goModFile
+	}
+	for testFile, content := range tmpl.testdata {
+		files[filepath.Join("testdata", testFile)] = content
 	}
 
-	return nil
-}
+	for path, content := range files {
+		var contents bytes.Buffer
+		err := template.Must(template.New("").Parse(content)).Execute(&contents, data)
+		if err != nil {
+			return err
+		}
 
-func createMainFile(name string) error {
-	const mainFileContent = `package main
-
-import tfvet "github.com/clintjedwards/tfvet/sdk"
-
-// Check is constructed so that we can fulfill the interface for the NewRule function below.
-type Check struct{}
-
-// Check is the logic of the linting rule. Consume the hclContent object and produce lint errors
-// as your linting rule sees fit.
-func (c *Check) Check(content []byte) ([]tfvet.RuleError, error) {
-	// We declare lintErrors here so that we can append to it as we find errors within the file.
-	var lintErrors []tfvet.RuleError
-
-	// All HCL files are comprised of two components: "blocks" and within those blocks, "attributes".
-	// The strategy for most rules is simply cycle through the blocks you're interested
-	// in and perform some logic to make sure its in the state you expect.
-	//
-	// ParseHCL gives us back our HCL file neatly parsed into a struct representing those
-	// nested blocks and attributes.
-	hclContent := tfvet.ParseHCL(content)
-
-	// This is where the actual linting logic is applied. Everytime we find an error we add
-	// it to the errors list with its location.
-	//
-	// The example below parses through labels to find the name for the correct terraform resource
-	// once it has found the correct resource it can perform some logic to make sure its in
-	// a certain state and then continue if it is or append a new lint error if it isn't.
-	//
-	// The below is just an example and many parts can/should be changed for your use case.
-	for _, block := range hclContent.Blocks {
-		for _, label := range block.Labels {
-
-
-			// <linting logic belongs here>
-
-			// Once we find an error we log its location
-			location := tfvet.Range{
-				Start: tfvet.Position{
-					Line:   uint32(block.DefRange().Start.Line),
-					Column: uint32(block.DefRange().Start.Column),
-				},
-				End: tfvet.Position{
-					Line:   uint32(block.DefRange().End.Line),
-					Column: uint32(block.DefRange().End.Column),
-				},
+		generated := contents.Bytes()
+		if strings.HasSuffix(path, ".go") {
+			generated, err = format.Source(generated)
+			if err != nil {
+				return fmt.Errorf("could not format %s: %w", path, err)
 			}
+		}
 
-			// Every error we find we construct a "RuleError" struct and add it to our errors list.
-			lintErrors = append(lintErrors, tfvet.RuleError{
-				Suggestion:  "Use a different resource name than example",
-				Remediation: "resource \"google_compute_instance\" \"<new_name>\" {",
-				Location:    location,
-				Metadata: map[string]string{
-					"severity": "warning",
-					"example":  "Lorem ipsum dolor sit amet",
-				},
-			})
+		err = ioutil.WriteFile(filepath.Join(ruleDirPath, path), generated, 0644)
+		if err != nil {
+			return err
 		}
 	}
 
-	return lintErrors, nil
+	return nil
 }
 
+// placeholderID returns an ID for a new rule made from its name: its letters and digits in upper
+// case followed by a number, so that the rule has a valid ID until its author picks one.
+func placeholderID(name string) string {
+	const suffix = "001"
 
-func main() {
-	// We instantiate an instance of our check interface we filled out above so we can register
-	// it into the rule below.
-	newCheck := Check{}
+	id := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, name)
 
-	// Here we can fill out more information about the rule, it's purpose, and where to find more
-	// documentation.
-	// The documentation for each of these fields can be found looking at the sdk documentation
-	// here: https://pkg.go.dev/github.com/clintjedwards/tfvet/sdk#Rule
-	newRule := &tfvet.Rule{
-		Name:  "{{.Name}}",
-		Short: "<Short description on what this rule is for, shown to user whenever rule finds an error>",
-		Long: "<A longer description about what this rule is for. This is used as documentation.>",
-		Enabled: true,
-		Link:    "<This should be a hyperlink to additional documentation>",
-		Check:   &newCheck,
+	if id == "" {
+		id = "RULE"
+	}
+	if max := 20 - len(suffix); len(id) > max {
+		id = id[:max]
 	}
 
-	// Lastly we add our new rule so that it is properly registered.
-	tfvet.NewRule(newRule)
+	return id + suffix
 }
-`
 
-	currentDir, err := os.Getwd()
+// compileRule makes sure the rule and its test compile. Rules with their own go.mod first have
+// their dependencies resolved, which fills in go.sum.
+func compileRule(ruleDirPath string, resolveDependencies bool) ([]byte, error) {
+	golangBinaryPath, err := exec.LookPath("go")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	const mainFileName = "main.go"
-	const rulesDirName = "rules"
-	ruleDirPath := fmt.Sprintf("%s/%s/%s", currentDir, rulesDirName, name)
-	mainFilePath := fmt.Sprintf("%s/%s", ruleDirPath, mainFileName)
+	if resolveDependencies {
+		output, err := utils.ExecuteCmd(golangBinaryPath, []string{"mod", "tidy"}, nil, ruleDirPath)
+		if err != nil {
+			return output, err
+		}
+	}
 
-	file, err := os.Create(mainFilePath)
-	if err != nil {
-		return err
+	// Vet compiles both the rule and its test without having to run them.
+	return utils.ExecuteCmd(golangBinaryPath, []string{"vet", "."}, nil, ruleDirPath)
+}
+
+// sdkVersion returns the version of the sdk generated rules require, which is the version of the
+// running tfvet. Development builds don't match a released version and use the latest one instead.
+func sdkVersion() string {
+	semverString := strings.Split(Version, "_")[0]
+
+	version, err := semver.NewVersion(semverString)
+	if err != nil || version.Major() != 2 {
+		return "latest"
 	}
-	defer file.Close()
 
-	tmpl := template.Must(template.New("").Parse(mainFileContent))
-	err = tmpl.Execute(file, struct {
-		Name string
-	}{
-		Name: name,
-	})
-	if err != nil {
-		return err
+	return "v" + version.String()
+}
+
+// templateNames returns the names of all rule templates, sorted.
+func templateNames() []string {
+	names := []string{}
+	for name := range ruleTemplates {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return nil
+	return names
+}
+
+// templateDescriptions returns a line describing each rule template.
+func templateDescriptions() string {
+	var descriptions strings.Builder
+	for _, name := range templateNames() {
+		fmt.Fprintf(&descriptions, "  %-20s %s\n", name, ruleTemplates[name].description)
+	}
+
+	return descriptions.String()
 }
@@ -3,9 +3,9 @@ package rule
 import (
 	"bytes"
 	"fmt"
-	"html/template"
 	"log"
 	"strings"
+	"text/template"
 
 	"github.com/clintjedwards/polyfmt"
 	"github.com/spf13/cobra"
@@ -28,7 +28,7 @@ func runDescribe(cmd *cobra.Command, args []string) error {
 		log.Fatal(err)
 	}
 
-	state, err := newState("", format)
+	state, err := newState("", format, false)
 	if err != nil {
 		return err
 	}
@@ -45,24 +45,59 @@ func runDescribe(cmd *cobra.Command, args []string) error {
 {{.Short}}
 
 {{.Long}}
-Enabled: {{.Enabled}} | Link: {{.Link}}`
+Enabled: {{.Enabled}} | Link: {{.Link}}
+{{- if .Category}}
+Category: {{.Category}}
+{{- end}}
+{{- if .Tags}}
+Tags: {{.Tags}}
+{{- end}}
+{{- if .Providers}}
+Providers: {{.Providers}}
+{{- end}}
+{{- if .BadExamples}}
+
+Examples of code this rule reports:
+{{- range .BadExamples}}
+
+{{.}}
+{{- end}}
+{{- end}}
+{{- if .GoodExamples}}
+
+Examples of compliant code:
+{{- range .GoodExamples}}
+
+{{.}}
+{{- end}}
+{{- end}}`
 
 	var tpl bytes.Buffer
 	t := template.Must(template.New("tmp").Parse(describeTmpl))
 	_ = t.Execute(&tpl, struct {
-		ID      string
-		Name    string
-		Short   string
-		Long    string
-		Enabled bool
-		Link    string
+		ID           string
+		Name         string
+		Short        string
+		Long         string
+		Enabled      bool
+		Link         string
+		Category     string
+		Tags         string
+		Providers    string
+		GoodExamples []string
+		BadExamples  []string
 	}{
-		ID:      rule.ID,
-		Name:    rule.Name,
-		Short:   rule.Short,
-		Long:    strings.TrimPrefix(rule.Long, "\n"),
-		Enabled: rule.Enabled,
-		Link:    rule.Link,
+		ID:           rule.ID,
+		Name:         rule.Name,
+		Short:        rule.Short,
+		Long:         strings.TrimPrefix(rule.Long, "\n"),
+		Enabled:      rule.Enabled,
+		Link:         rule.Link,
+		Category:     string(rule.Category),
+		Tags:         strings.Join(rule.Tags, ", "),
+		Providers:    strings.Join(rule.Providers, ", "),
+		GoodExamples: formatExamples(rule.GoodExamples),
+		BadExamples:  formatExamples(rule.BadExamples),
 	})
 
 	state.fmt.Println(tpl.String(), polyfmt.Pretty)
@@ -71,6 +106,25 @@ Enabled: {{.Enabled}} | Link: {{.Link}}`
 	return nil
 }
 
+// formatExamples highlights example snippets, if output may be colored, and indents them so they
+// stand out from the text around them.
+func formatExamples(examples []string) []string {
+	color := useColor()
+
+	formatted := []string{}
+	for _, example := range examples {
+		example = strings.Trim(example, "\n")
+		if color {
+			example = highlightHCL(example)
+		}
+
+		lines := strings.Split(example, "\n")
+		formatted = append(formatted, "    "+strings.Join(lines, "\n    "))
+	}
+
+	return formatted
+}
+
 func init() {
 	CmdRule.AddCommand(cmdRuleDescribe)
 }
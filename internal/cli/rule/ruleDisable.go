@@ -24,10 +24,11 @@ func runDisable(cmd *cobra.Command, args []string) error {
 		log.Fatal(err)
 	}
 
-	state, err := newState("Disabling rule", format)
+	state, err := newState("Disabling rule", format, true)
 	if err != nil {
 		return err
 	}
+	defer state.cfg.Unlock()
 
 	err = state.cfg.SetRuleEnabled(ruleset, rule, false)
 	if err != nil {
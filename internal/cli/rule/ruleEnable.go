@@ -24,10 +24,11 @@ func runEnable(cmd *cobra.Command, args []string) error {
 		log.Fatal(err)
 	}
 
-	state, err := newState("Enabling rule", format)
+	state, err := newState("Enabling rule", format, true)
 	if err != nil {
 		return err
 	}
+	defer state.cfg.Unlock()
 
 	err = state.cfg.SetRuleEnabled(ruleset, rule, true)
 	if err != nil {
@@ -0,0 +1,406 @@
+package rule
+
+// ruleTemplate is the starting point for a new rule. Every template is a working rule with
+// examples and test data, so that the generated rule builds and passes its tests as is.
+type ruleTemplate struct {
+	description string
+	// main is the template of the rule's main.go.
+	main string
+	// testdata are the files placed in the rule's testdata directory, keyed by file name.
+	testdata map[string]string
+}
+
+// defaultRuleTemplate is used when no template is given.
+const defaultRuleTemplate = "required-attribute"
+
+var ruleTemplates = map[string]ruleTemplate{
+	"required-attribute": {
+		description: "resources of a type must set an attribute",
+		main:        requiredAttributeMain,
+		testdata: map[string]string{
+			"good.tf": `resource "google_compute_instance" "web" {
+  machine_type = "e2-medium"
+  labels = {
+    team = "platform"
+  }
+}
+`,
+			"bad.tf": `resource "google_compute_instance" "web" {
+  machine_type = "e2-medium"
+}
+`,
+		},
+	},
+	"naming": {
+		description: "resources and data sources must follow a naming convention",
+		main:        namingMain,
+		testdata: map[string]string{
+			"good.tf": `resource "google_storage_bucket" "build_logs" {
+  name     = "build-logs"
+  location = "US"
+}
+`,
+			"bad.tf": `resource "google_storage_bucket" "BuildLogs" {
+  name     = "build-logs"
+  location = "US"
+}
+`,
+		},
+	},
+	"forbidden-resource": {
+		description: "resources of certain types must not be used",
+		main:        forbiddenResourceMain,
+		testdata: map[string]string{
+			"good.tf": `resource "google_project_iam_member" "viewer" {
+  project = "my-project"
+  role    = "roles/viewer"
+  member  = "group:viewers@example.com"
+}
+`,
+			"bad.tf": `resource "google_project_iam_policy" "project" {
+  project     = "my-project"
+  policy_data = data.google_iam_policy.admin.policy_data
+}
+`,
+		},
+	},
+	"module-rule": {
+		description: "attribute values, which can come from variables and locals, must be allowed",
+		main:        moduleRuleMain,
+		testdata: map[string]string{
+			"variables.tf": `variable "machine_type" {
+  type    = string
+  default = "n2-highmem-96"
+}
+`,
+			"good.tf": `resource "google_compute_instance" "web" {
+  machine_type = "e2-medium"
+}
+`,
+			"bad.tf": `resource "google_compute_instance" "batch" {
+  machine_type = var.machine_type
+}
+`,
+		},
+	},
+}
+
+const ruleTestFile = `package main
+
+import (
+	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/sdk/sdktest"
+)
+
+// TestRule checks that the rule reports its bad examples and the files in testdata starting with
+// "bad", while leaving its good examples and the files starting with "good" alone.
+func TestRule(t *testing.T) {
+	sdktest.TestExamples(t, rule)
+	sdktest.TestData(t, rule, "testdata")
+}
+`
+
+const goModFile = `module {{.Name}}
+
+go 1.15
+
+require github.com/clintjedwards/tfvet/v2 {{.Version}}
+`
+
+const requiredAttributeMain = `package main
+
+import tfvet "github.com/clintjedwards/tfvet/v2/sdk"
+
+// Check is constructed so that we can fulfill the interface for the rule below.
+type Check struct{}
+
+// Check is the logic of the linting rule. Consume the hclContent object and produce lint errors
+// as your linting rule sees fit.
+func (c *Check) Check(content []byte) ([]tfvet.RuleError, error) {
+	// We declare lintErrors here so that we can append to it as we find errors within the file.
+	var lintErrors []tfvet.RuleError
+
+	// All HCL files are comprised of two components: "blocks" and within those blocks, "attributes".
+	// The strategy for most rules is simply cycle through the blocks you're interested
+	// in and perform some logic to make sure its in the state you expect.
+	//
+	// ParseHCL gives us back our HCL file neatly parsed into a struct representing those
+	// nested blocks and attributes.
+	hclContent := tfvet.ParseHCL(content)
+
+	// This is where the actual linting logic is applied. Everytime we find an error we add
+	// it to the errors list with its location.
+	//
+	// The example below finds every resource of a certain type and checks that it sets an
+	// attribute. The sdk has more helpers like these for finding blocks and attributes; Blocks,
+	// NestedBlocks and WalkBlocks for example.
+	for _, resource := range tfvet.Resources(hclContent, "google_compute_instance") {
+		if tfvet.Attribute(resource, "labels") != nil {
+			continue
+		}
+
+		// Report constructs a "RuleError" pointing at the resource, which we fill out further
+		// and add to our errors list.
+		lintError := tfvet.Report(resource, "Label all compute instances")
+		lintError.Remediation = "labels = { team = \"<team>\" }"
+
+		lintErrors = append(lintErrors, lintError)
+	}
+
+	return lintErrors, nil
+}
+
+// rule holds information about the rule, its purpose, and where to find more documentation.
+// The documentation for each of these fields can be found looking at the sdk documentation
+// here: https://pkg.go.dev/github.com/clintjedwards/tfvet/v2/sdk#Rule
+var rule = &tfvet.Rule{
+	// ID is how users refer to this rule. Replace the placeholder with something short and stable,
+	// like "AWS001".
+	ID:        "{{.ID}}",
+	Name:      "{{.Name}}",
+	Short:     "<Short description on what this rule is for, shown to user whenever rule finds an error>",
+	Long:      "<A longer description about what this rule is for. This is used as documentation.>",
+	Enabled:   true,
+	Link:      "<This should be a hyperlink to additional documentation>",
+	Category:  tfvet.CategoryCorrectness,
+	Providers: []string{"google"},
+	// Examples are shown by "tfvet rule describe" and checked by the rule's tests.
+	BadExamples: []string{` + "`" + `resource "google_compute_instance" "web" {
+  machine_type = "e2-medium"
+}` + "`" + `},
+	GoodExamples: []string{` + "`" + `resource "google_compute_instance" "web" {
+  machine_type = "e2-medium"
+  labels       = { team = "platform" }
+}` + "`" + `},
+	Check: &Check{},
+}
+
+func main() {
+	// Lastly we add our new rule so that it is properly registered.
+	tfvet.NewRule(rule)
+}
+`
+
+const namingMain = `package main
+
+import (
+	"fmt"
+	"regexp"
+
+	tfvet "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+// namePattern is the naming convention resources and data sources must follow.
+var namePattern = regexp.MustCompile(` + "`^[a-z][a-z0-9_]*$`" + `)
+
+// Check is constructed so that we can fulfill the interface for the rule below.
+type Check struct{}
+
+// Check reports every resource and data source whose name doesn't follow the naming convention.
+func (c *Check) Check(content []byte) ([]tfvet.RuleError, error) {
+	var lintErrors []tfvet.RuleError
+
+	hclContent := tfvet.ParseHCL(content)
+
+	for _, blockType := range []string{"resource", "data"} {
+		for _, block := range tfvet.Blocks(hclContent, blockType) {
+			// The first label is the type, the second one the name.
+			if len(block.Labels) < 2 || namePattern.MatchString(block.Labels[1]) {
+				continue
+			}
+
+			lintError := tfvet.Report(block, fmt.Sprintf("Name %s using lower case letters, digits and underscores",
+				block.Labels[1]))
+			lintError.Remediation = fmt.Sprintf("%s %q %q {", blockType, block.Labels[0], "<snake_case_name>")
+
+			lintErrors = append(lintErrors, lintError)
+		}
+	}
+
+	return lintErrors, nil
+}
+
+// rule holds information about the rule, its purpose, and where to find more documentation.
+// The documentation for each of these fields can be found looking at the sdk documentation
+// here: https://pkg.go.dev/github.com/clintjedwards/tfvet/v2/sdk#Rule
+var rule = &tfvet.Rule{
+	// ID is how users refer to this rule. Replace the placeholder with something short and stable,
+	// like "AWS001".
+	ID:       "{{.ID}}",
+	Name:     "{{.Name}}",
+	Short:    "Resource names should only use lower case letters, digits and underscores.",
+	Long:     "<A longer description about what this rule is for. This is used as documentation.>",
+	Enabled:  true,
+	Link:     "<This should be a hyperlink to additional documentation>",
+	Category: tfvet.CategoryStyle,
+	Tags:     []string{"naming"},
+	// Examples are shown by "tfvet rule describe" and checked by the rule's tests.
+	BadExamples:  []string{` + "`" + `resource "google_storage_bucket" "BuildLogs" {}` + "`" + `},
+	GoodExamples: []string{` + "`" + `resource "google_storage_bucket" "build_logs" {}` + "`" + `},
+	Check:        &Check{},
+}
+
+func main() {
+	tfvet.NewRule(rule)
+}
+`
+
+const forbiddenResourceMain = `package main
+
+import (
+	"fmt"
+
+	tfvet "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+// forbidden are the resource types that must not be used, along with what to use instead.
+var forbidden = map[string]string{
+	"google_project_iam_policy":  "google_project_iam_member",
+	"google_project_iam_binding": "google_project_iam_member",
+}
+
+// Check is constructed so that we can fulfill the interface for the rule below.
+type Check struct{}
+
+// Check reports every resource of a forbidden type.
+func (c *Check) Check(content []byte) ([]tfvet.RuleError, error) {
+	var lintErrors []tfvet.RuleError
+
+	hclContent := tfvet.ParseHCL(content)
+
+	for _, resource := range tfvet.Blocks(hclContent, "resource") {
+		if len(resource.Labels) < 2 {
+			continue
+		}
+
+		replacement, ok := forbidden[resource.Labels[0]]
+		if !ok {
+			continue
+		}
+
+		lintError := tfvet.Report(resource, fmt.Sprintf("Use %s instead of %s", replacement, resource.Labels[0]))
+		lintError.Remediation = fmt.Sprintf("resource %q %q {", replacement, resource.Labels[1])
+
+		lintErrors = append(lintErrors, lintError)
+	}
+
+	return lintErrors, nil
+}
+
+// rule holds information about the rule, its purpose, and where to find more documentation.
+// The documentation for each of these fields can be found looking at the sdk documentation
+// here: https://pkg.go.dev/github.com/clintjedwards/tfvet/v2/sdk#Rule
+var rule = &tfvet.Rule{
+	// ID is how users refer to this rule. Replace the placeholder with something short and stable,
+	// like "AWS001".
+	ID:    "{{.ID}}",
+	Name:  "{{.Name}}",
+	Short: "Authoritative IAM resources remove access granted outside of this configuration.",
+	Long: "<A longer description about what this rule is for. This is used as documentation.>",
+	Enabled:   true,
+	Link:      "<This should be a hyperlink to additional documentation>",
+	Category:  tfvet.CategorySecurity,
+	Tags:      []string{"iam"},
+	Providers: []string{"google"},
+	// Examples are shown by "tfvet rule describe" and checked by the rule's tests.
+	BadExamples: []string{` + "`" + `resource "google_project_iam_binding" "viewers" {
+  project = "my-project"
+  role    = "roles/viewer"
+  members = ["group:viewers@example.com"]
+}` + "`" + `},
+	GoodExamples: []string{` + "`" + `resource "google_project_iam_member" "viewers" {
+  project = "my-project"
+  role    = "roles/viewer"
+  member  = "group:viewers@example.com"
+}` + "`" + `},
+	Check: &Check{},
+}
+
+func main() {
+	tfvet.NewRule(rule)
+}
+`
+
+const moduleRuleMain = `package main
+
+import (
+	"fmt"
+
+	tfvet "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// allowedMachineTypes are the machine types instances may use.
+var allowedMachineTypes = map[string]bool{
+	"e2-small":  true,
+	"e2-medium": true,
+}
+
+// Check is constructed so that we can fulfill the interface for the rule below.
+type Check struct{}
+
+// CheckModule reports instances with a machine type that isn't allowed. Unlike a plain Check it
+// is given the module the file belongs to, which evaluates expressions that refer to variables,
+// locals and functions; the machine type doesn't have to be written out in the resource.
+func (c *Check) CheckModule(content []byte, module *tfvet.Module) ([]tfvet.RuleError, error) {
+	var lintErrors []tfvet.RuleError
+
+	hclContent := tfvet.ParseHCL(content)
+
+	for _, instance := range tfvet.Resources(hclContent, "google_compute_instance") {
+		attribute := tfvet.Attribute(instance, "machine_type")
+		if attribute == nil {
+			continue
+		}
+
+		// Values that are only known once terraform runs, like variables without a value, are
+		// unknown. They can't be checked, so they are skipped.
+		machineType := module.Evaluate(attribute.Expr)
+		if !machineType.IsKnown() || machineType.IsNull() || !machineType.Type().Equals(cty.String) {
+			continue
+		}
+
+		if allowedMachineTypes[machineType.AsString()] {
+			continue
+		}
+
+		lintErrors = append(lintErrors, tfvet.Report(attribute,
+			fmt.Sprintf("Machine type %s is not allowed; use e2-small or e2-medium", machineType.AsString())))
+	}
+
+	return lintErrors, nil
+}
+
+// rule holds information about the rule, its purpose, and where to find more documentation.
+// The documentation for each of these fields can be found looking at the sdk documentation
+// here: https://pkg.go.dev/github.com/clintjedwards/tfvet/v2/sdk#Rule
+var rule = &tfvet.Rule{
+	// ID is how users refer to this rule. Replace the placeholder with something short and stable,
+	// like "AWS001".
+	ID:        "{{.ID}}",
+	Name:      "{{.Name}}",
+	Short:     "Instances should use one of the allowed machine types.",
+	Long:      "<A longer description about what this rule is for. This is used as documentation.>",
+	Enabled:   true,
+	Link:      "<This should be a hyperlink to additional documentation>",
+	Category:  tfvet.CategoryCost,
+	Providers: []string{"google"},
+	// Examples are shown by "tfvet rule describe" and checked by the rule's tests.
+	BadExamples: []string{` + "`" + `variable "machine_type" {
+  default = "n2-highmem-96"
+}
+
+resource "google_compute_instance" "batch" {
+  machine_type = var.machine_type
+}` + "`" + `},
+	GoodExamples: []string{` + "`" + `resource "google_compute_instance" "web" {
+  machine_type = "e2-medium"
+}` + "`" + `},
+	ModuleCheck: &Check{},
+}
+
+func main() {
+	tfvet.NewRule(rule)
+}
+`
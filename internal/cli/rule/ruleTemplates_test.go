@@ -0,0 +1,58 @@
+package rule
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+func TestPlaceholderID(t *testing.T) {
+	tests := map[string]string{
+		"bucket_names":                        "BUCKETNAMES001",
+		"s3-encryption2":                      "S3ENCRYPTION2001",
+		"a_really_long_rule_name_for_testing": "AREALLYLONGRULENA001",
+		"___":                                 "RULE001",
+	}
+
+	for name, expected := range tests {
+		id := placeholderID(name)
+		if id != expected {
+			t.Errorf("%s: expected %q; got %q", name, expected, id)
+		}
+		if !models.IsValidRuleID(id) {
+			t.Errorf("%s: %q is not a valid rule ID", name, id)
+		}
+	}
+}
+
+// TestRuleTemplates checks that every template executes and generates valid go code, which
+// createRuleDir formats, declaring the placeholder ID.
+func TestRuleTemplates(t *testing.T) {
+	for _, name := range templateNames() {
+		dir := t.TempDir()
+
+		err := createRuleDir(dir, "bucket_names", ruleTemplates[name], true)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		main, err := ioutil.ReadFile(filepath.Join(dir, "main.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(main), `"BUCKETNAMES001"`) {
+			t.Errorf("%s: expected main.go to declare the placeholder ID:\n%s", name, main)
+		}
+
+		for file := range ruleTemplates[name].testdata {
+			_, err := ioutil.ReadFile(filepath.Join(dir, "testdata", file))
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+}
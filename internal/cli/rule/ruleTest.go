@@ -0,0 +1,167 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/clintjedwards/polyfmt"
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/spf13/cobra"
+)
+
+var cmdRuleTest = &cobra.Command{
+	Use:   "test <ruleset> [rule]",
+	Short: "Checks rules against their examples",
+	Long: `Checks rules against the examples they ship with.
+
+Each bad example is expected to be reported by the rule and each good example is expected to pass
+it. Rules are run even if they are disabled. If no rule is given all rules of the ruleset are tested.`,
+	Example: `$ tfvet rule test core
+$ tfvet rule test core CORE001`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runTest,
+}
+
+func runTest(cmd *cobra.Command, args []string) error {
+	rulesetName := args[0]
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	state, err := newState("", format, false)
+	if err != nil {
+		return err
+	}
+
+	ruleset, err := state.cfg.GetRuleset(rulesetName)
+	if err != nil {
+		errText := fmt.Sprintf("could not test ruleset %s: %v", rulesetName, err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	rules := ruleset.Rules
+	if len(args) == 2 {
+		rule, err := state.cfg.GetRule(rulesetName, args[1])
+		if err != nil {
+			errText := fmt.Sprintf("could not test rule %s: %v", args[1], err)
+			state.fmt.PrintErr(errText)
+			state.fmt.Finish()
+			return errors.New(errText)
+		}
+		rules = []models.Rule{rule}
+	}
+
+	// Examples are linted as if they were the only file of a module in an empty directory, so
+	// that nothing around them influences the result.
+	dir, err := ioutil.TempDir("", "tfvet_rule_test")
+	if err != nil {
+		errText := fmt.Sprintf("could not create directory for examples: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+	defer os.RemoveAll(dir)
+
+	failed := 0
+	for _, rule := range rules {
+		failed += testRule(state, ruleset, rule, dir)
+	}
+
+	if failed > 0 {
+		errText := fmt.Sprintf("%d example(s) did not behave as expected", failed)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	state.fmt.PrintSuccess("All examples behaved as expected")
+	state.fmt.Finish()
+	return nil
+}
+
+// testRule lints the examples of a single rule and returns how many of them did not behave as
+// expected.
+func testRule(state *state, ruleset models.Ruleset, rule models.Rule, dir string) int {
+	if len(rule.GoodExamples) == 0 && len(rule.BadExamples) == 0 {
+		state.fmt.Println(fmt.Sprintf("[%s] has no examples", rule.ID), polyfmt.Pretty)
+		return 0
+	}
+
+	path := filepath.Join(dir, "main.tf")
+	switch {
+	case rule.AppliesTo(models.Configuration):
+	case rule.AppliesTo(models.VariableDefinitions):
+		path = filepath.Join(dir, "terraform.tfvars")
+	default:
+		state.fmt.Println(fmt.Sprintf("[%s] only checks plans; its examples can't be tested", rule.ID),
+			polyfmt.Pretty)
+		return 0
+	}
+
+	rule.Enabled = true
+	ruleset.Enabled = true
+	ruleset.Rules = []models.Rule{rule}
+
+	ruleLinter := linter.New([]models.Ruleset{ruleset})
+	defer ruleLinter.Close()
+
+	failed := 0
+	check := func(kind string, index int, example string, wantErrors bool) {
+		name := fmt.Sprintf("[%s] %s example %d", rule.ID, kind, index+1)
+
+		result, err := ruleLinter.LintFile(path, []byte(example))
+		switch {
+		case err != nil:
+			state.fmt.PrintErr(fmt.Sprintf("%s: could not parse example: %v", name, err))
+		case len(result.Failures) > 0:
+			state.fmt.PrintErr(fmt.Sprintf("%s: rule failed to run: %v", name, result.Failures[0].Err))
+		case diagnosticError(result.Diagnostics) != "":
+			state.fmt.PrintErr(fmt.Sprintf("%s: rule returned an error: %s", name, diagnosticError(result.Diagnostics)))
+		case wantErrors && len(result.LintErrors) == 0:
+			state.fmt.PrintErr(fmt.Sprintf("%s: expected the rule to report it; it didn't", name))
+		case !wantErrors && len(result.LintErrors) > 0:
+			state.fmt.PrintErr(fmt.Sprintf("%s: expected no lint errors; got %d: %s",
+				name, len(result.LintErrors), result.LintErrors[0].RuleErr.Suggestion))
+		default:
+			state.fmt.PrintSuccess(name)
+			return
+		}
+
+		failed++
+	}
+
+	for index, example := range rule.BadExamples {
+		check("bad", index, example, true)
+	}
+
+	for index, example := range rule.GoodExamples {
+		check("good", index, example, false)
+	}
+
+	return failed
+}
+
+// diagnosticError returns the message of the first error among the diagnostics, if there is one.
+// Examples the rule couldn't fully check can't be said to pass or fail it.
+func diagnosticError(diagnostics []linter.RuleDiagnostic) string {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Diagnostic.Severity == models.DiagnosticError {
+			return diagnostic.Diagnostic.Message
+		}
+	}
+
+	return ""
+}
+
+func init() {
+	CmdRule.AddCommand(cmdRuleTest)
+}
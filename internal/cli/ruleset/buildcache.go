@@ -0,0 +1,326 @@
+package ruleset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+	"github.com/clintjedwards/tfvet/v2/internal/utils"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// buildCache is the struct representation of the build cache file kept in each ruleset directory.
+// It records the source hash of every rule at the time it was last successfully compiled so that
+// unchanged rules can skip compilation on update.
+type buildCache struct {
+	Rules []buildCacheEntry `hcl:"rule,block"`
+}
+
+// buildCacheEntry is the last recorded build of a single rule directory.
+type buildCacheEntry struct {
+	Dir  string `hcl:"dir,label"`
+	Hash string `hcl:"hash"`
+	// ID is the rule ID the binary was stored under. Binaries serving several rules are stored
+	// under the ID derived from their directory name instead.
+	ID string `hcl:"id,optional"`
+	// Rules are the IDs of the rules served by a binary serving several rules. Each of them is a
+	// link to the binary.
+	Rules []string `hcl:"rules,optional"`
+	// Declarative is set for rules written in HCL, which are stored as their definition instead of
+	// a binary.
+	Declarative bool `hcl:"declarative,optional"`
+}
+
+// readBuildCache returns the build cache for a ruleset. A missing cache file is not an error, it
+// simply results in an empty cache.
+func readBuildCache(ruleset string) (*buildCache, error) {
+	cache := &buildCache{}
+
+	_, err := os.Stat(appcfg.BuildCacheFilePath(ruleset))
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+
+	err = hclsimple.DecodeFile(appcfg.BuildCacheFilePath(ruleset), nil, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// writeBuildCache writes the build cache for a ruleset to disk. It is written atomically, so that
+// an interrupted write never leaves behind a partial cache.
+func writeBuildCache(ruleset string, cache *buildCache) error {
+	f := hclwrite.NewEmptyFile()
+
+	gohcl.EncodeIntoBody(cache, f.Body())
+
+	return utils.WriteFileAtomic(appcfg.BuildCacheFilePath(ruleset), f.Bytes(), 0644)
+}
+
+// get returns the recorded build for a rule directory or an empty entry if there is none.
+func (c *buildCache) get(dir string) buildCacheEntry {
+	for _, entry := range c.Rules {
+		if entry.Dir == dir {
+			return entry
+		}
+	}
+
+	return buildCacheEntry{Dir: dir}
+}
+
+// set records the build of a rule directory, replacing any previous entry.
+func (c *buildCache) set(dir, hash, id string, rules []string, declarative bool) {
+	entry := buildCacheEntry{Dir: dir, Hash: hash, ID: id, Rules: rules, Declarative: declarative}
+
+	for index := range c.Rules {
+		if c.Rules[index].Dir == dir {
+			c.Rules[index] = entry
+			return
+		}
+	}
+
+	c.Rules = append(c.Rules, entry)
+}
+
+// prune removes the entries of rule directories that no longer exist.
+func (c *buildCache) prune(dirs map[string]bool) {
+	entries := []buildCacheEntry{}
+	for _, entry := range c.Rules {
+		if dirs[entry.Dir] {
+			entries = append(entries, entry)
+		}
+	}
+
+	c.Rules = entries
+}
+
+// files returns the names of all binaries, links to binaries and declarative rules recorded in the
+// cache.
+func (c *buildCache) files() map[string]bool {
+	files := map[string]bool{}
+
+	for _, entry := range c.Rules {
+		id := entry.ID
+		if id == "" {
+			id = generateHash(entry.Dir)
+		}
+
+		if entry.Declarative {
+			files[appcfg.DeclarativeRuleFileName(id)] = true
+			continue
+		}
+		files[id] = true
+
+		for _, rule := range entry.Rules {
+			files[rule] = true
+		}
+	}
+
+	return files
+}
+
+// removeStale removes the binaries and links that were recorded in the cache before, given as
+// previous, but aren't anymore. This cleans up after rules that were removed from the ruleset
+// or moved to another binary.
+func (c *buildCache) removeStale(ruleset string, previous map[string]bool) error {
+	current := c.files()
+
+	for name := range previous {
+		if current[name] {
+			continue
+		}
+
+		err := os.Remove(appcfg.RulePath(ruleset, name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hashRuleSource returns a hash of everything that affects the compiled output of a rule:
+// all go and hcl files within the rule directory, the go files of packages within the ruleset
+// repository the rule imports, and the go.mod/go.sum files of both the rule directory and the
+// repository root.
+func hashRuleSource(rulePath, repoPath string) (string, error) {
+	rulePath = filepath.Clean(rulePath)
+	repoPath = filepath.Clean(repoPath)
+
+	files := []string{}
+
+	err := filepath.Walk(rulePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		if strings.HasSuffix(path, ".go") || strings.HasSuffix(path, ".hcl") {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	imported, err := importedFiles(rulePath, repoPath)
+	if err != nil {
+		return "", err
+	}
+	files = append(files, imported...)
+
+	for _, dir := range []string{rulePath, repoPath} {
+		for _, name := range []string{"go.mod", "go.sum"} {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				files = append(files, path)
+			}
+		}
+	}
+
+	// Packages within the rule directory are both walked and imported.
+	unique := map[string]bool{}
+	for _, file := range files {
+		unique[file] = true
+	}
+	files = []string{}
+	for file := range unique {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	digest := sha256.New()
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		relPath, err := filepath.Rel(repoPath, file)
+		if err != nil {
+			return "", err
+		}
+
+		// Include the path so that moving code between files also counts as a change.
+		fmt.Fprintf(digest, "%s\x00%d\x00", relPath, len(contents))
+		digest.Write(contents)
+	}
+
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// importedFiles returns the go files, excluding tests, of every package within the rule's module
+// that the rule imports, directly or through other packages of the module. Shared packages, like
+// rules/internal or pkg, are often kept outside of the rule directory.
+//
+// Imports are read from the source rather than asking the go tool, which would need the module's
+// dependencies to be downloaded just to hash the rule. Build constraints are ignored, so files
+// that aren't compiled into the rule might be included; this only costs an unneeded rebuild.
+func importedFiles(rulePath, repoPath string) ([]string, error) {
+	modulePath, moduleDir, err := ruleModule(rulePath, repoPath)
+	if err != nil || modulePath == "" {
+		return nil, err
+	}
+
+	files := []string{}
+	visited := map[string]bool{rulePath: true}
+	queue := []string{rulePath}
+
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+
+			path := filepath.Join(dir, name)
+			if dir != rulePath {
+				files = append(files, path)
+			}
+
+			// Files that don't parse are still hashed above; the compiler reports what's wrong.
+			file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+			if err != nil {
+				continue
+			}
+
+			for _, spec := range file.Imports {
+				importPath, err := strconv.Unquote(spec.Path.Value)
+				if err != nil || (importPath != modulePath && !strings.HasPrefix(importPath, modulePath+"/")) {
+					continue
+				}
+
+				pkgDir := filepath.Join(moduleDir, filepath.FromSlash(strings.TrimPrefix(importPath, modulePath)))
+				if visited[pkgDir] {
+					continue
+				}
+				visited[pkgDir] = true
+
+				if _, err := os.Stat(pkgDir); err == nil {
+					queue = append(queue, pkgDir)
+				}
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// ruleModule returns the path and directory of the module a rule belongs to, read from the
+// closest go.mod between the rule directory and the repository root. An empty path is returned
+// if there is none.
+func ruleModule(rulePath, repoPath string) (path, dir string, err error) {
+	dir = rulePath
+	for {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			return modulePath(contents), dir, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", err
+		}
+
+		if dir == repoPath || dir == filepath.Dir(dir) {
+			return "", "", nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// modulePath returns the module path declared in the contents of a go.mod file.
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+
+	return ""
+}
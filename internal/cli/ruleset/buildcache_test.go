@@ -0,0 +1,224 @@
+package ruleset
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+)
+
+// useConfigPath points the config to a temporary directory for the rest of the test.
+func useConfigPath(t *testing.T) {
+	t.Helper()
+
+	previous, ok := os.LookupEnv("TFVET_CONFIG_PATH")
+	os.Setenv("TFVET_CONFIG_PATH", t.TempDir())
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("TFVET_CONFIG_PATH", previous)
+			return
+		}
+		os.Unsetenv("TFVET_CONFIG_PATH")
+	})
+}
+
+func TestRemoveStale(t *testing.T) {
+	useConfigPath(t)
+
+	err := os.MkdirAll(appcfg.RulesetPath("example"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := &buildCache{Rules: []buildCacheEntry{
+		{Dir: "aws", ID: "aws", Rules: []string{"AWS001", "AWS002"}},
+		{Dir: "names", ID: "EX001"},
+		{Dir: "old", ID: "EX002"},
+		{Dir: "buckets", ID: "AWS003", Declarative: true},
+	}}
+
+	for name := range previous.files() {
+		err := ioutil.WriteFile(appcfg.RulePath("example", name), []byte(name), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// AWS002 moved to its own binary, old was removed from the ruleset and buckets changed its ID.
+	current := &buildCache{Rules: []buildCacheEntry{
+		{Dir: "aws", ID: "aws", Rules: []string{"AWS001"}},
+		{Dir: "names", ID: "EX001"},
+		{Dir: "s3", ID: "AWS002"},
+		{Dir: "buckets", ID: "AWS004", Declarative: true},
+	}}
+
+	err = current.removeStale("example", previous.files())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept := []string{"aws", "AWS001", "AWS002", "EX001"}
+	removed := []string{"EX002", appcfg.DeclarativeRuleFileName("AWS003")}
+
+	for _, name := range kept {
+		if _, err := os.Stat(appcfg.RulePath("example", name)); err != nil {
+			t.Errorf("expected %s to be kept; got %v", name, err)
+		}
+	}
+
+	for _, name := range removed {
+		if _, err := os.Stat(appcfg.RulePath("example", name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", name)
+		}
+	}
+}
+
+func TestWriteBuildCache(t *testing.T) {
+	useConfigPath(t)
+
+	err := os.MkdirAll(appcfg.RulesetPath("example"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := &buildCache{Rules: []buildCacheEntry{{Dir: "names", Hash: "abc", ID: "EX001"}}}
+	err = writeBuildCache("example", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := readBuildCache("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := read.get("names"); entry.Hash != "abc" || entry.ID != "EX001" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	files, err := ioutil.ReadDir(appcfg.RulesetPath("example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only the build cache to be left behind; got %d files", len(files))
+	}
+}
+
+func TestHashRuleSource(t *testing.T) {
+	repo := t.TempDir()
+	rule := filepath.Join(repo, "rules", "names")
+
+	writeFiles(t, map[string]string{
+		filepath.Join(repo, "go.mod"):    "module example.com/rules\n",
+		filepath.Join(rule, "main.go"):   "package main\n",
+		filepath.Join(rule, "check.go"):  "package main\n",
+		filepath.Join(rule, "README.md"): "# names\n",
+	})
+
+	hash := func() string {
+		t.Helper()
+
+		hash, err := hashRuleSource(rule, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	rename := func(from, to string) {
+		t.Helper()
+
+		err := os.MkdirAll(filepath.Dir(filepath.Join(rule, to)), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Rename(filepath.Join(rule, from), filepath.Join(rule, to))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	original := hash()
+	if hash() != original {
+		t.Fatal("expected unchanged source to have the same hash")
+	}
+
+	writeFiles(t, map[string]string{filepath.Join(rule, "README.md"): "# renamed\n"})
+	if hash() != original {
+		t.Error("expected files that aren't compiled not to change the hash")
+	}
+
+	rename("check.go", "checks.go")
+	renamed := hash()
+	if renamed == original {
+		t.Error("expected renaming a file to change the hash")
+	}
+
+	rename("checks.go", filepath.Join("internal", "checks.go"))
+	moved := hash()
+	if moved == renamed || moved == original {
+		t.Error("expected moving a file to another directory to change the hash")
+	}
+
+	writeFiles(t, map[string]string{filepath.Join(repo, "go.mod"): "module example.com/rules\n\ngo 1.15\n"})
+	if hash() == moved {
+		t.Error("expected changing the repository's go.mod to change the hash")
+	}
+}
+
+func TestHashRuleSourceImports(t *testing.T) {
+	repo := t.TempDir()
+	rule := filepath.Join(repo, "rules", "names")
+
+	writeFiles(t, map[string]string{
+		filepath.Join(repo, "go.mod"): "module example.com/rules\n",
+		filepath.Join(rule, "main.go"): `package main
+
+import (
+	"fmt"
+
+	shared "example.com/rules/rules/internal/shared"
+)
+`,
+		filepath.Join(repo, "rules", "internal", "shared", "shared.go"): `package shared
+
+import "example.com/rules/pkg/naming"
+`,
+		filepath.Join(repo, "rules", "internal", "shared", "shared_test.go"): "package shared\n",
+		filepath.Join(repo, "pkg", "naming", "naming.go"):                    "package naming\n",
+		filepath.Join(repo, "pkg", "other", "other.go"):                      "package other\n",
+	})
+
+	tests := map[string]struct {
+		file    string
+		changes bool
+	}{
+		"imported package":              {file: filepath.Join(repo, "rules", "internal", "shared", "shared.go"), changes: true},
+		"package imported transitively": {file: filepath.Join(repo, "pkg", "naming", "naming.go"), changes: true},
+		"test of imported package":      {file: filepath.Join(repo, "rules", "internal", "shared", "shared_test.go")},
+		"package not imported":          {file: filepath.Join(repo, "pkg", "other", "other.go")},
+	}
+
+	for name, test := range tests {
+		before, err := hashRuleSource(rule, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents, err := ioutil.ReadFile(test.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, map[string]string{test.file: string(contents) + "\n// " + name + "\n"})
+
+		after, err := hashRuleSource(rule, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if (before != after) != test.changes {
+			t.Errorf("%s: expected the hash to change: %t; got before %s and after %s", name, test.changes, before, after)
+		}
+	}
+}
@@ -4,14 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/clintjedwards/polyfmt"
 	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+	"github.com/clintjedwards/tfvet/v2/internal/declarative"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
 	getter "github.com/hashicorp/go-getter/v2"
@@ -42,8 +50,10 @@ type rulesetInfo struct {
 	Version string `hcl:"version"`
 }
 
-// newState returns a new initialized state object
-func newState(initialFmtMsg, format string) (*state, error) {
+// newState returns a new initialized state object.
+// If lock is set the config is locked against changes from other tfvet processes; callers must
+// call state.cfg.Unlock() once they are done.
+func newState(initialFmtMsg, format string, lock bool) (*state, error) {
 	clifmt, err := polyfmt.NewFormatter(polyfmt.Mode(format))
 	if err != nil {
 		log.Fatal(err)
@@ -51,7 +61,12 @@ func newState(initialFmtMsg, format string) (*state, error) {
 	}
 	clifmt.Print(initialFmtMsg, polyfmt.Pretty)
 
-	cfg, err := appcfg.GetConfig()
+	getConfig := appcfg.GetConfig
+	if lock {
+		getConfig = appcfg.GetConfigLocked
+	}
+
+	cfg, err := getConfig()
 	if err != nil {
 		errText := fmt.Sprintf("error reading config file %q: %v", appcfg.ConfigFilePath(), err)
 		clifmt.PrintErr(errText)
@@ -91,17 +106,68 @@ func getRemoteRulesetInfo(repoPath string) (rulesetInfo, error) {
 	return info, nil
 }
 
+// ruleBuild is the outcome of compiling a single rule directory. A directory is compiled into a
+// single binary, which may serve one rule or several.
+type ruleBuild struct {
+	// dirName is the name of the directory within the rules folder; "." when the rules folder
+	// itself is the binary's main package.
+	dirName string
+	// hashedID is the ID derived from the rule's directory name. It is used when the rule does not
+	// declare its own ID and is kept as an alias when it does.
+	hashedID string
+	// binaryID is the ID the rule's binary is currently stored under.
+	binaryID string
+	hash     string
+	cached   bool // true if the rule was unchanged since the last build and was not recompiled.
+	duration time.Duration
+	output   []byte // the full compiler output; useful for debugging failed builds.
+	err      error
+	rules    []models.Rule // information about the rules served, as reported by the binary itself.
+
+	// declarative builds are rules written in HCL. They aren't compiled; their definition is
+	// stored in place of a binary and run by tfvet itself.
+	declarative bool
+}
+
+// name returns a name for the build suitable for messages.
+func (b *ruleBuild) name() string {
+	if b.dirName == "." {
+		return "rules"
+	}
+
+	return b.dirName
+}
+
+// linked returns whether the build's binary serves several rules, each of them a link to the
+// binary. Binaries serving a single rule are stored under the rule's ID instead.
+func (b *ruleBuild) linked() bool {
+	return len(b.rules) > 1
+}
+
 // buildAllRules builds the plugins(rules are plugins) and places the binary
-// underneath the correct ruleset directory.
-func buildAllRules(s *state, ruleset string) error {
+// underneath the correct ruleset directory. It returns the information of all rules built.
+//
+// Usually each directory within the rules folder is compiled into its own binary. A binary can
+// serve a single rule (sdk.NewRule) or several (sdk.NewRuleset). If the rules folder contains go
+// files itself, it is compiled into a single binary instead and its directories are treated as
+// packages of it. Binaries serving several rules are stored once, with a link for each rule.
+//
+// Rules are built within the staged directory of the ruleset (see appcfg.StagingRuleset) so that
+// nothing about the installed ruleset changes until the caller decides to swap it in.
+//
+// Rules are compiled concurrently. Rules whose source hash matches the hash recorded at their last
+// successful build are skipped.
+func buildAllRules(s *state, ruleset string) ([]models.Rule, error) {
+	staged := appcfg.StagingRuleset(ruleset)
+
 	s.fmt.Print("Opening rules directory")
 
-	file, err := os.Open(appcfg.RepoRulesPath(ruleset))
+	file, err := os.Open(appcfg.RepoRulesPath(staged))
 	if err != nil {
 		errText := fmt.Sprintf("could not open rules folder: %v", err)
 		s.fmt.PrintErr(errText)
 		s.fmt.Finish()
-		return errors.New(errText)
+		return nil, errors.New(errText)
 	}
 	defer file.Close()
 
@@ -111,73 +177,324 @@ func buildAllRules(s *state, ruleset string) error {
 		errText := fmt.Sprintf("could not read rules folder: %v", err)
 		s.fmt.PrintErr(errText)
 		s.fmt.Finish()
-		return errors.New(errText)
+		return nil, errors.New(errText)
+	}
+
+	cache, err := readBuildCache(staged)
+	if err != nil {
+		errText := fmt.Sprintf("could not read build cache: %v", err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
 	}
 
 	startTime := time.Now()
-	count := 0
 
-	// Rules are separated into directories. We iterate through directories and build whats inside
-	// them.
-	for _, file := range fileList {
-		if !file.IsDir() {
-			continue
+	builds := []*ruleBuild{}
+	hashedIDs := map[string]string{}
+	for _, dirName := range ruleDirNames(fileList) {
+		// Rules that don't declare an ID get one derived from a hash of the dirname(aka the rule
+		// folder name). Rules are always initially compiled under this ID and renamed once we
+		// know the ID they declare. It also stays an alias of the rule, so two directories can't
+		// share it even if their rules declare IDs.
+		hashedID := generateHash(dirName)
+		if dupDirName, exists := hashedIDs[hashedID]; exists {
+			errText := fmt.Sprintf("rule directories %s and %s hash to the same ID %s; rename one of the"+
+				" directories, declaring IDs does not avoid this", dupDirName, dirName, hashedID)
+			s.fmt.PrintErr(errText)
+			s.fmt.Finish()
+			return nil, errors.New(errText)
 		}
+		hashedIDs[hashedID] = dirName
 
-		// Get the dirname and not the full path.
-		// Sometimes file.Name will return the full path based on what is passed to file.Open.
-		dirName := filepath.Base(file.Name())
+		_, err := os.Stat(filepath.Join(appcfg.RepoRulesPath(staged), dirName, declarative.FileName))
 
-		s.fmt.Print(fmt.Sprintf("Compiling %s", dirName))
+		builds = append(builds, &ruleBuild{
+			dirName:     dirName,
+			hashedID:    hashedID,
+			binaryID:    hashedID,
+			declarative: err == nil,
+		})
+	}
 
-		rawRulePath := fmt.Sprintf("%s/%s", appcfg.RepoRulesPath(ruleset), dirName)
+	sort.Slice(builds, func(i, j int) bool { return builds[i].dirName < builds[j].dirName })
 
-		// We take the hash of the dirname(aka the rule folder name) and make it the rule ID.
-		// This allows us to have consistent ids for rules without having to have the ruleset
-		// (aka the user) define them.
-		//
-		// TODO(clintjedwards): Collision detection isn't built in and will probably break
-		// things if it ever does happen.
-		ruleID := generateHash(dirName)
+	s.fmt.Print(fmt.Sprintf("Compiling %d rule(s)", len(builds)))
 
-		// We build here by pointing the golang binary on the user's computer to the rule path.
-		// This causes the compiler to compile whatever is in that path and spit out a binary
-		// where ever we want.
-		_, err := buildRule(rawRulePath, appcfg.RulePath(ruleset, ruleID))
-		if err != nil {
-			errText := fmt.Sprintf("could not build rule %s: %v", dirName, err)
-			s.fmt.PrintErr(errText)
-			s.fmt.Finish()
-			return errors.New(errText)
+	// The formatter is not safe for concurrent use, so workers only record their results and
+	// all printing is done once every build has finished.
+	var wg sync.WaitGroup
+	limiter := make(chan struct{}, runtime.NumCPU())
+	for _, build := range builds {
+		wg.Add(1)
+		go func(build *ruleBuild) {
+			defer wg.Done()
+			limiter <- struct{}{}
+			defer func() { <-limiter }()
+
+			if build.declarative {
+				loadDeclarativeRule(staged, build, cache.get(build.dirName))
+				return
+			}
+
+			compileRule(staged, build, cache.get(build.dirName))
+		}(build)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, build := range builds {
+		if build.err != nil {
+			failed++
+			s.fmt.PrintErr(fmt.Sprintf("Failed to compile %s after %.2fs: %v\n%s",
+				build.name(), build.duration.Seconds(), build.err, build.output))
+			continue
 		}
 
-		s.fmt.Print(fmt.Sprintf("Collecting rule info for: %s", dirName))
-		newRule, err := getRuleInfo(ruleset, ruleID)
-		if err != nil {
-			errText := fmt.Sprintf("could not build rule %s: %v", dirName, err)
-			s.fmt.PrintErr(errText)
-			s.fmt.Finish()
-			return errors.New(errText)
+		switch {
+		case build.cached:
+			s.fmt.PrintSuccess(fmt.Sprintf("Skipped %s; unchanged since last build", build.name()))
+		case build.declarative:
+			s.fmt.PrintSuccess(fmt.Sprintf("Loaded declarative rule %s", build.name()))
+			continue
+		default:
+			s.fmt.PrintSuccess(fmt.Sprintf("Compiled %s in %.2fs", build.name(), build.duration.Seconds()))
 		}
 
-		err = s.cfg.UpsertRule(ruleset, newRule)
+		// Declarative rules were already read when loading them.
+		if build.declarative {
+			continue
+		}
+
+		s.fmt.Print(fmt.Sprintf("Collecting rule info for: %s", build.name()))
+		build.rules, err = listRules(staged, build.binaryID)
 		if err != nil {
-			errText := fmt.Sprintf("could not upsert rule %s to config file: %v", dirName, err)
-			s.fmt.PrintErr(errText)
-			s.fmt.Finish()
-			return errors.New(errText)
+			failed++
+			build.err = err
+			s.fmt.PrintErr(fmt.Sprintf("could not get rule info for %s: %v", build.name(), err))
+			continue
 		}
-		count++
+	}
+
+	// Rule IDs must be unique within a ruleset. We check this before moving any binaries so that
+	// one rule can't overwrite another.
+	for _, problem := range checkRuleIDs(builds) {
+		failed++
+		s.fmt.PrintErr(problem)
+	}
+
+	// Binaries and links recorded before this build; the ones that aren't needed anymore are
+	// removed below.
+	previousFiles := cache.files()
+
+	for _, build := range builds {
+		if build.err != nil {
+			continue
+		}
+
+		if failed == 0 && !build.declarative && !build.linked() && build.rules[0].ID != build.binaryID {
+			err := os.Rename(appcfg.RulePath(staged, build.binaryID), appcfg.RulePath(staged, build.rules[0].ID))
+			if err != nil {
+				errText := fmt.Sprintf("could not move rule %s to its ID %s: %v", build.name(), build.rules[0].ID, err)
+				s.fmt.PrintErr(errText)
+				s.fmt.Finish()
+				return nil, errors.New(errText)
+			}
+			build.binaryID = build.rules[0].ID
+		}
+
+		links := []string{}
+		if failed == 0 && build.linked() {
+			for _, rule := range build.rules {
+				err := linkRule(staged, build.binaryID, rule.ID)
+				if err != nil {
+					errText := fmt.Sprintf("could not link rule %s to %s: %v", rule.ID, build.name(), err)
+					s.fmt.PrintErr(errText)
+					s.fmt.Finish()
+					return nil, errors.New(errText)
+				}
+				links = append(links, rule.ID)
+			}
+		}
+
+		cache.set(build.dirName, build.hash, build.binaryID, links, build.declarative)
+	}
+
+	// Rules that were removed from the ruleset leave their binaries behind otherwise.
+	dirs := map[string]bool{}
+	for _, build := range builds {
+		dirs[build.dirName] = true
+	}
+	cache.prune(dirs)
+
+	err = cache.removeStale(staged, previousFiles)
+	if err != nil {
+		errText := fmt.Sprintf("could not remove rules no longer in ruleset: %v", err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
+	}
+
+	// We save the cache even if some rules failed so that fixing a single broken rule doesn't
+	// require recompiling the rest of them.
+	err = writeBuildCache(staged, cache)
+	if err != nil {
+		errText := fmt.Sprintf("could not write build cache: %v", err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
+	}
+
+	if failed > 0 {
+		errText := fmt.Sprintf("could not build %d of %d rule(s)", failed, len(builds))
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
+	}
+
+	count := 0
+	cached := 0
+	newRules := []models.Rule{}
+	for _, build := range builds {
+		for _, newRule := range build.rules {
+			// Keep the hashed ID around so that anything referring to the rule by it still works.
+			// Rules sharing a binary and declarative rules always declared their IDs, so they never
+			// had a hashed one.
+			if !build.linked() && !build.declarative && !strings.EqualFold(newRule.ID, build.hashedID) {
+				newRule.Aliases = append(newRule.Aliases, build.hashedID)
+			}
+
+			// Rules in different rulesets may share an ID since they are always addressed by ruleset,
+			// but it's confusing enough that we let the user know.
+			for _, other := range s.cfg.Rulesets {
+				if other.Name == ruleset {
+					continue
+				}
+
+				for _, otherRule := range other.Rules {
+					if strings.EqualFold(otherRule.ID, newRule.ID) {
+						s.fmt.PrintErr(fmt.Sprintf("warning: rule ID %s (%s) is also used by ruleset %s",
+							newRule.ID, build.name(), other.Name))
+					}
+				}
+			}
+
+			newRules = append(newRules, newRule)
+		}
+
+		if build.cached {
+			cached += len(build.rules)
+			continue
+		}
+		count += len(build.rules)
 	}
 
 	duration := time.Since(startTime)
 	durationSeconds := float64(duration) / float64(time.Second)
-	timePerRule := float64(duration) / float64(count)
 
-	s.fmt.PrintSuccess(fmt.Sprintf("Compiled %d rule(s) in %.2fs (average %.2fms/rule)",
-		count, durationSeconds, timePerRule/float64(time.Millisecond)))
+	s.fmt.PrintSuccess(fmt.Sprintf("Compiled %d rule(s) and skipped %d unchanged rule(s) in %.2fs",
+		count, cached, durationSeconds))
 
-	return nil
+	return newRules, nil
+}
+
+// checkRuleIDs makes sure the rules of all successful builds declare valid IDs that are unique
+// within the ruleset and aren't reserved. Builds serving an offending rule have their error set;
+// a description of each problem is returned.
+func checkRuleIDs(builds []*ruleBuild) []string {
+	problems := []string{}
+
+	ruleIDs := map[string]string{}
+	for _, build := range builds {
+		if build.err != nil {
+			continue
+		}
+
+		for _, rule := range build.rules {
+			id := strings.ToUpper(rule.ID)
+
+			if !models.IsValidRuleID(rule.ID) {
+				build.err = fmt.Errorf("invalid rule ID %q", rule.ID)
+				problems = append(problems, fmt.Sprintf("rule %s declares invalid ID %q; IDs must be between 1"+
+					" and 20 alphanumeric characters", build.name(), rule.ID))
+				break
+			}
+
+			if appcfg.IsReservedRuleID(rule.ID) {
+				build.err = fmt.Errorf("reserved rule ID %q", rule.ID)
+				problems = append(problems, fmt.Sprintf("rule %s declares the ID %q, which is reserved by tfvet;"+
+					" pick another ID", build.name(), rule.ID))
+				break
+			}
+
+			if dupDirName, exists := ruleIDs[id]; exists {
+				build.err = fmt.Errorf("duplicate rule ID %q", rule.ID)
+				problems = append(problems, fmt.Sprintf("rules %s and %s both use the ID %s; rule IDs must be"+
+					" unique within a ruleset", dupDirName, build.name(), rule.ID))
+				break
+			}
+			ruleIDs[id] = build.name()
+		}
+	}
+
+	return problems
+}
+
+// ruleDirNames returns the names of the directories within the rules folder, given its contents,
+// that are each built into a binary. Rules are separated into directories, unless the rules folder
+// is a program itself, in which case "." is the only directory returned.
+func ruleDirNames(fileList []os.FileInfo) []string {
+	dirNames := []string{}
+	for _, file := range fileList {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".go") {
+			return []string{"."}
+		}
+
+		if !file.IsDir() {
+			continue
+		}
+
+		// Get the dirname and not the full path.
+		// Sometimes file.Name will return the full path based on what is passed to file.Open.
+		dirNames = append(dirNames, filepath.Base(file.Name()))
+	}
+
+	return dirNames
+}
+
+// compileRule builds a single rule and records the outcome in the build passed in.
+// If the rule's source hash matches the last recorded build and the binary is still present the
+// compilation is skipped.
+func compileRule(ruleset string, build *ruleBuild, last buildCacheEntry) {
+	startTime := time.Now()
+	defer func() { build.duration = time.Since(startTime) }()
+
+	rawRulePath := fmt.Sprintf("%s/%s", appcfg.RepoRulesPath(ruleset), build.dirName)
+
+	hash, err := hashRuleSource(rawRulePath, appcfg.RepoPath(ruleset))
+	if err != nil {
+		build.err = fmt.Errorf("could not hash rule source: %w", err)
+		return
+	}
+	build.hash = hash
+
+	lastID := last.ID
+	if lastID == "" {
+		lastID = build.hashedID
+	}
+
+	if hash == last.Hash {
+		if _, err := os.Stat(appcfg.RulePath(ruleset, lastID)); err == nil {
+			build.binaryID = lastID
+			build.cached = true
+			return
+		}
+	}
+
+	// We build here by pointing the golang binary on the user's computer to the rule path.
+	// This causes the compiler to compile whatever is in that path and spit out a binary
+	// where ever we want.
+	build.output, build.err = buildRule(rawRulePath, appcfg.RulePath(ruleset, build.hashedID))
 }
 
 // verifyRuleset makes sure a downloaded ruleset has the correct structure.
@@ -207,3 +524,103 @@ func verifyRuleset(path string, info rulesetInfo) error {
 
 	return nil
 }
+
+// loadDeclarativeRule reads a declarative rule and stores its definition in the ruleset directory,
+// where the linter picks it up.
+func loadDeclarativeRule(ruleset string, build *ruleBuild, last buildCacheEntry) {
+	startTime := time.Now()
+	defer func() { build.duration = time.Since(startTime) }()
+
+	rawRulePath := fmt.Sprintf("%s/%s", appcfg.RepoRulesPath(ruleset), build.dirName)
+
+	hash, err := hashRuleSource(rawRulePath, appcfg.RepoPath(ruleset))
+	if err != nil {
+		build.err = fmt.Errorf("could not hash rule source: %w", err)
+		return
+	}
+	build.hash = hash
+
+	if hash == last.Hash && last.Declarative {
+		if rule, err := declarative.Load(appcfg.DeclarativeRulePath(ruleset, last.ID)); err == nil {
+			build.binaryID = last.ID
+			build.rules = []models.Rule{*rule}
+			build.cached = true
+			return
+		}
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(rawRulePath, declarative.FileName))
+	if err != nil {
+		build.err = err
+		return
+	}
+
+	rule, err := declarative.Parse(declarative.FileName, content)
+	if err != nil {
+		build.err = err
+		return
+	}
+
+	build.err = ioutil.WriteFile(appcfg.DeclarativeRulePath(ruleset, rule.ID), content, 0644)
+	build.binaryID = rule.ID
+	build.rules = []models.Rule{*rule}
+}
+
+// linkRule makes the rule with the given ID point to a binary serving several rules. The link is
+// relative so that it survives the ruleset being moved to its permanent location.
+//
+// Creating symbolic links requires privileges on Windows, so where that fails the rule is made a
+// hard link to the binary instead, or as a last resort a copy of it. Rules linked that way are
+// served by a plugin process of their own.
+func linkRule(ruleset, binaryID, ruleID string) error {
+	if strings.EqualFold(binaryID, ruleID) {
+		return nil
+	}
+
+	binaryPath := appcfg.RulePath(ruleset, binaryID)
+	rulePath := appcfg.RulePath(ruleset, ruleID)
+
+	err := os.Remove(rulePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Symlink(binaryID, rulePath)
+	if err == nil {
+		return nil
+	}
+
+	err = os.Link(binaryPath, rulePath)
+	if err == nil {
+		return nil
+	}
+
+	return copyFile(binaryPath, rulePath)
+}
+
+// copyFile copies the file at srcPath, along with its permissions, to dstPath.
+func copyFile(srcPath, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
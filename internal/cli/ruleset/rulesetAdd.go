@@ -4,7 +4,6 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
@@ -16,10 +15,10 @@ import (
 	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
 	"github.com/clintjedwards/tfvet/v2/internal/utils"
 	models "github.com/clintjedwards/tfvet/v2/sdk"
-	"github.com/hashicorp/go-hclog"
-	"github.com/hashicorp/go-plugin"
 	"github.com/otiai10/copy"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -47,6 +46,10 @@ following rules:
 
 For more information on tfvet ruleset repository requirements and structure see:
 github.com/clintjedwards/tfvet-ruleset-example
+
+Rule IDs only have to be unique within a ruleset; rules are always addressed by their ruleset, so
+rules of different rulesets may share an ID. Since that's easily confused, a warning is printed for
+every rule whose ID is also used by another installed ruleset.
 `,
 	Example: `$ tfvet add github.com/example/tfvet-ruleset-aws
 $ tfvet add ~/tmp/tfvet-ruleset-example`,
@@ -80,75 +83,93 @@ func generateHash(s string) string {
 	return fmt.Sprintf(hash[0:5])
 }
 
-// getRulePluginClient returns the grpc plugin client, the rule plugin client, and a possible error.
-// the rule plugin client is used to communicate with the rule plugins and from this client you can
-// run commands that work just like regular methods against the plugins.
-//
-// YOU MUST call kill() on the returned plugin.Client object or it will cause memory leaks.
-//
-// TODO(clintjedwards): This by default just discards any logs from the client.
-// Change this to only do this above the loglevel debug.
-func getRulePluginClient(ruleset, ruleID string) (client *plugin.Client, rule tfvetPlugin.RuleDefinition, err error) {
-
-	tmpPluginName := "tfvetPlugin"
-
-	client = plugin.NewClient(&plugin.ClientConfig{
-		HandshakeConfig: tfvetPlugin.Handshake,
-		Plugins: map[string]plugin.Plugin{
-			tmpPluginName: &tfvetPlugin.TfvetRulePlugin{},
-		},
-		Cmd: exec.Command(appcfg.RulePath(ruleset, ruleID)),
-		Logger: hclog.New(&hclog.LoggerOptions{
-			Output: ioutil.Discard,
-			Level:  0,
-			Name:   "plugin",
-		}),
-		Stderr:           nil,
-		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
-	})
-
-	rpcClient, err := client.Client()
+// getRuleInfo retrieves information by calling the GetRuleInfo method on the rule plugin.
+// If the rule does not declare its own ID, the ID it was stored under is used.
+func getRuleInfo(ruleset, ruleID string) (models.Rule, error) {
+	c, plugin, err := tfvetPlugin.Connect(appcfg.RulePath(ruleset, ruleID), nil)
 	if err != nil {
-		client.Kill()
-		return nil, nil, fmt.Errorf("could not connect to rule plugin %s: %v", ruleID, err)
+		return models.Rule{}, fmt.Errorf("could not get rule info for %s: %w", ruleID, err)
 	}
+	defer c.Kill()
 
-	raw, err := rpcClient.Dispense(tmpPluginName)
+	response, err := plugin.GetRuleInfo(&proto.GetRuleInfoRequest{RuleId: ruleID})
 	if err != nil {
-		client.Kill()
-		return nil, nil, fmt.Errorf("could not connect to rule plugin %s: %v", ruleID, err)
+		return models.Rule{}, fmt.Errorf("could not get rule info for %s: %w", ruleID, err)
 	}
 
-	plugin, ok := raw.(tfvetPlugin.RuleDefinition)
-	if !ok {
-		client.Kill()
-		return nil, nil, fmt.Errorf("could not convert rule plugin %s: %v", ruleID, err)
-	}
+	return ruleFromInfo(response.RuleInfo, ruleID), nil
+}
 
-	return client, plugin, nil
+// listRules retrieves information about all rules served by the plugin binary stored under the
+// given ID.
+func listRules(ruleset, binaryID string) ([]models.Rule, error) {
+	return listRulesAt(appcfg.RulePath(ruleset, binaryID), binaryID)
 }
 
-// getRuleInfo retrieves information by calling the GetRuleInfo method on the rule plugin.
-func getRuleInfo(ruleset, ruleID string) (models.Rule, error) {
-	c, plugin, err := getRulePluginClient(ruleset, ruleID)
+// listRulesAt retrieves information about all rules served by the plugin binary at the given
+// path. Rules that don't declare their own ID get the given one. Plugins built with versions of
+// the sdk that predate plugins serving several rules don't support listing their rules, so they
+// are asked about their single rule instead.
+func listRulesAt(path, binaryID string) ([]models.Rule, error) {
+	c, plugin, err := tfvetPlugin.Connect(path, nil)
 	if err != nil {
-		return models.Rule{}, fmt.Errorf("could not get rule info for %s: %w", ruleID, err)
+		return nil, fmt.Errorf("could not list rules: %w", err)
 	}
 	defer c.Kill()
 
-	response, err := plugin.GetRuleInfo(&proto.GetRuleInfoRequest{})
+	response, err := plugin.ListRules(&proto.ListRulesRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		info, err := plugin.GetRuleInfo(&proto.GetRuleInfoRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("could not get rule info: %w", err)
+		}
+
+		return []models.Rule{ruleFromInfo(info.RuleInfo, binaryID)}, nil
+	}
 	if err != nil {
-		return models.Rule{}, fmt.Errorf("could not get rule info for %s: %w", ruleID, err)
+		return nil, fmt.Errorf("could not list rules: %w", err)
+	}
+
+	if len(response.Rules) == 0 {
+		return nil, errors.New("plugin does not serve any rules")
+	}
+
+	rules := []models.Rule{}
+	for _, info := range response.Rules {
+		rules = append(rules, ruleFromInfo(info, binaryID))
+	}
+
+	return rules, nil
+}
+
+// ruleFromInfo converts the rule information reported by a plugin. Rules that don't declare their
+// own ID get the given one.
+func ruleFromInfo(info *proto.RuleInfo, defaultID string) models.Rule {
+	id := info.Id
+	if id == "" {
+		id = defaultID
+	}
+
+	fileKinds := []models.FileKind{}
+	for _, kind := range info.FileKinds {
+		fileKinds = append(fileKinds, models.FileKind(kind))
 	}
 
 	return models.Rule{
-		ID:      ruleID,
-		Name:    response.RuleInfo.Name,
-		Short:   response.RuleInfo.Short,
-		Long:    response.RuleInfo.Long,
-		Link:    response.RuleInfo.Link,
-		Enabled: response.RuleInfo.Enabled,
-	}, nil
+		ID:           id,
+		Name:         info.Name,
+		Short:        info.Short,
+		Long:         info.Long,
+		Link:         info.Link,
+		Enabled:      info.Enabled,
+		FileKinds:    fileKinds,
+		Category:     models.Category(info.Category),
+		Tags:         info.Tags,
+		Providers:    info.Providers,
+		GoodExamples: info.GoodExamples,
+		BadExamples:  info.BadExamples,
+		FileOnly:     info.FileOnly,
+	}
 }
 
 // buildRule builds the rule/plugin from srcPath and stores it in dstPath
@@ -178,10 +199,11 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		log.Fatal(err)
 	}
 
-	state, err := newState("Adding ruleset", format)
+	state, err := newState("Adding ruleset", format, true)
 	if err != nil {
 		return err
 	}
+	defer state.cfg.Unlock()
 
 	state.fmt.Print("Checking for duplicates")
 
@@ -227,34 +249,28 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	}
 	state.fmt.PrintSuccess("Verified ruleset")
 
-	// Add new ruleset to configuration file.
-	state.fmt.Print("Adding ruleset to config")
-	err = state.cfg.AddRuleset(models.Ruleset{
-		Name:       info.Name,
-		Version:    info.Version,
-		Repository: repoLocation,
-		Enabled:    true,
-	})
-	if err != nil {
-		errText := fmt.Sprintf("could not add ruleset: %v", err)
+	if state.cfg.RulesetExists(info.Name) {
+		errText := fmt.Sprintf("ruleset %s already exists; use `tfvet ruleset update`"+
+			" to manipulate already added rulesets", info.Name)
 		state.fmt.PrintErr(errText)
 		state.fmt.Finish()
 		return errors.New(errText)
 	}
 
-	// Move downloaded ruleset repository to the permanent location within config.
-	state.fmt.Print("Moving ruleset to permanent config location")
-	err = moveRepo(info.Name, tmpDownloadPath)
+	// Everything is assembled and built in a staging directory first so that a failure doesn't
+	// leave behind a half installed ruleset.
+	state.fmt.Print("Staging ruleset")
+	err = stageRuleset(info.Name, tmpDownloadPath)
 	if err != nil {
-		errText := fmt.Sprintf("could not move ruleset repository: %v", err)
+		errText := fmt.Sprintf("could not stage ruleset: %v", err)
 		state.fmt.PrintErr(errText)
 		state.fmt.Finish()
 		return errors.New(errText)
 	}
-	state.fmt.PrintSuccess("New ruleset added")
+	defer removeStagedRuleset(info.Name)
 
 	// Find all rules within the ruleset and build them using the go compiler.
-	err = buildAllRules(state, info.Name)
+	rules, err := buildAllRules(state, info.Name)
 	if err != nil {
 		errText := fmt.Sprintf("could not build ruleset rules: %v", err)
 		state.fmt.PrintErr(errText)
@@ -262,6 +278,34 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return errors.New(errText)
 	}
 
+	// Move the built ruleset to the permanent location within config.
+	state.fmt.Print("Moving ruleset to permanent config location")
+	swap, err := swapRuleset(info.Name)
+	if err != nil {
+		errText := fmt.Sprintf("could not move ruleset: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	// Add new ruleset to configuration file.
+	state.fmt.Print("Adding ruleset to config")
+	err = state.cfg.AddRuleset(models.Ruleset{
+		Name:       info.Name,
+		Version:    info.Version,
+		Repository: repoLocation,
+		Enabled:    true,
+		Rules:      rules,
+	})
+	if err != nil {
+		_ = swap.rollback()
+		errText := fmt.Sprintf("could not add ruleset: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+	swap.commit()
+
 	state.fmt.PrintSuccess(fmt.Sprintf("Successfully added ruleset: %s v%s", info.Name, info.Version))
 	state.fmt.Finish()
 	return nil
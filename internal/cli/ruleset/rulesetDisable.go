@@ -23,10 +23,11 @@ func runDisable(cmd *cobra.Command, args []string) error {
 		log.Fatal(err)
 	}
 
-	state, err := newState("Disabling ruleset", format)
+	state, err := newState("Disabling ruleset", format, true)
 	if err != nil {
 		return err
 	}
+	defer state.cfg.Unlock()
 
 	err = state.cfg.SetRulesetEnabled(ruleset, false)
 	if err != nil {
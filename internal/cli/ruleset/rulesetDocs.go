@@ -0,0 +1,509 @@
+package ruleset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/clintjedwards/tfvet/v2/internal/declarative"
+	"github.com/clintjedwards/tfvet/v2/internal/utils"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/spf13/cobra"
+)
+
+var cmdRulesetDocs = &cobra.Command{
+	Use:   "docs <path>",
+	Short: "Generates documentation for a ruleset",
+	Long: `Generates markdown documentation for the ruleset at the given path, usually the ruleset
+repository being worked on.
+
+The rules are built and asked for their details, so the documentation always matches what the
+rules report about themselves. One page is written per rule, along with an index page listing all
+rules. Terraform files found in a rule's testdata directory are included as examples; rules built
+into a binary with other rules use testdata/<rule ID> instead.
+
+Rules don't declare a severity; lint errors carry their own. Pages list each rule's category in its
+place.
+
+Pages written by an earlier run for rules that no longer exist are removed. Other files in the
+output directory are left alone.
+`,
+	Example: `$ tfvet ruleset docs .
+$ tfvet ruleset docs ~/tfvet-ruleset-example --out wiki/`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDocs,
+}
+
+// generatedMarker is included in every page generated so that pages of removed rules can be told
+// apart from files written by hand.
+const generatedMarker = "<!-- Generated by tfvet ruleset docs; do not edit. -->"
+
+// documentedRule is a rule along with the examples shown on its page.
+type documentedRule struct {
+	rule     models.Rule
+	examples []example
+}
+
+// example is a terraform file from a rule's testdata.
+type example struct {
+	name    string
+	content string
+}
+
+func runDocs(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outDir, err := cmd.Flags().GetString("out")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	state, err := newState("Generating ruleset documentation", format, false)
+	if err != nil {
+		return err
+	}
+
+	info, err := getRemoteRulesetInfo(path)
+	if err != nil {
+		errText := fmt.Sprintf("could not parse ruleset file: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	err = verifyRuleset(path, info)
+	if err != nil {
+		errText := fmt.Sprintf("could not verify ruleset: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	rules, err := state.documentRules(filepath.Join(path, "rules"))
+	if err != nil {
+		return err
+	}
+
+	err = utils.CreateDir(outDir)
+	if err != nil {
+		errText := fmt.Sprintf("could not create output directory: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	written := map[string]bool{}
+	for _, rule := range rules {
+		name := rule.rule.ID + ".md"
+		err := ioutil.WriteFile(filepath.Join(outDir, name), []byte(formatRulePage(info, rule)), 0644)
+		if err != nil {
+			errText := fmt.Sprintf("could not write documentation for rule %s: %v", rule.rule.ID, err)
+			state.fmt.PrintErr(errText)
+			state.fmt.Finish()
+			return errors.New(errText)
+		}
+		written[name] = true
+	}
+
+	err = ioutil.WriteFile(filepath.Join(outDir, "index.md"), []byte(formatIndexPage(info, rules)), 0644)
+	if err != nil {
+		errText := fmt.Sprintf("could not write documentation index: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+	written["index.md"] = true
+
+	removed, err := removeStalePages(outDir, written)
+	if err != nil {
+		errText := fmt.Sprintf("could not remove documentation of removed rules: %v", err)
+		state.fmt.PrintErr(errText)
+		state.fmt.Finish()
+		return errors.New(errText)
+	}
+
+	state.fmt.PrintSuccess(fmt.Sprintf("Documented %d rule(s) of ruleset %s in %s; removed %d stale page(s)",
+		len(rules), info.Name, outDir, removed))
+	state.fmt.Finish()
+
+	return nil
+}
+
+// documentRules builds the rules within the given rules folder and returns their details, sorted
+// by ID. Rules are built into a temporary directory, leaving the installed rulesets untouched.
+func (s *state) documentRules(rulesPath string) ([]documentedRule, error) {
+	fileList, err := ioutil.ReadDir(rulesPath)
+	if err != nil {
+		errText := fmt.Sprintf("could not read rules folder: %v", err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
+	}
+
+	buildDir, err := ioutil.TempDir("", "tfvet-docs")
+	if err != nil {
+		errText := fmt.Sprintf("could not create build directory: %v", err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
+	}
+	defer os.RemoveAll(buildDir)
+
+	rules := []documentedRule{}
+	for _, dirName := range ruleDirNames(fileList) {
+		dirPath := filepath.Join(rulesPath, dirName)
+
+		dirRules, err := s.readRuleInfo(dirPath, dirName, buildDir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rule := range dirRules {
+			// Rules sharing a binary keep their examples apart by rule ID.
+			testdata := filepath.Join(dirPath, "testdata")
+			if len(dirRules) > 1 {
+				testdata = filepath.Join(testdata, rule.ID)
+			}
+
+			examples, err := readExamples(testdata)
+			if err != nil {
+				errText := fmt.Sprintf("could not read examples of rule %s: %v", rule.ID, err)
+				s.fmt.PrintErr(errText)
+				s.fmt.Finish()
+				return nil, errors.New(errText)
+			}
+
+			rules = append(rules, documentedRule{rule: rule, examples: examples})
+		}
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].rule.ID < rules[j].rule.ID })
+
+	return rules, nil
+}
+
+// readRuleInfo returns the details of the rules within a rule directory. Declarative rules are
+// read directly; everything else is compiled into buildDir and asked about the rules it serves.
+func (s *state) readRuleInfo(dirPath, dirName, buildDir string) ([]models.Rule, error) {
+	name := dirName
+	if dirName == "." {
+		name = "rules"
+	}
+
+	if _, err := os.Stat(filepath.Join(dirPath, declarative.FileName)); err == nil {
+		rule, err := declarative.Load(filepath.Join(dirPath, declarative.FileName))
+		if err != nil {
+			errText := fmt.Sprintf("could not load declarative rule %s: %v", name, err)
+			s.fmt.PrintErr(errText)
+			s.fmt.Finish()
+			return nil, errors.New(errText)
+		}
+
+		return []models.Rule{*rule}, nil
+	}
+
+	s.fmt.Print(fmt.Sprintf("Compiling %s", name))
+
+	hashedID := generateHash(dirName)
+	binaryPath := filepath.Join(buildDir, hashedID)
+
+	output, err := buildRule(dirPath, binaryPath)
+	if err != nil {
+		errText := fmt.Sprintf("could not compile %s: %v\n%s", name, err, output)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
+	}
+
+	rules, err := listRulesAt(binaryPath, hashedID)
+	if err != nil {
+		errText := fmt.Sprintf("could not get rule info for %s: %v", name, err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
+	}
+
+	return rules, nil
+}
+
+// readExamples returns the terraform files within a rule's testdata directory. A missing
+// directory means the rule has no examples.
+func readExamples(dir string) ([]example, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	examples := []example{}
+	for _, file := range files {
+		if file.IsDir() || !isExampleFile(file.Name()) {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		examples = append(examples, example{name: file.Name(), content: string(content)})
+	}
+
+	return examples, nil
+}
+
+// isExampleFile returns whether the file is a terraform file that can be shown as an example.
+func isExampleFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json") ||
+		strings.HasSuffix(name, ".tfvars")
+}
+
+// removeStalePages removes pages generated by an earlier run that weren't written this time, since
+// the rules they document no longer exist. It returns how many pages were removed.
+func removeStalePages(outDir string, written map[string]bool) (int, error) {
+	files, err := ioutil.ReadDir(outDir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, file := range files {
+		if file.IsDir() || written[file.Name()] || !strings.HasSuffix(file.Name(), ".md") {
+			continue
+		}
+
+		path := filepath.Join(outDir, file.Name())
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return removed, err
+		}
+
+		if !bytes.HasPrefix(content, []byte(generatedMarker)) {
+			continue
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// formatRulePage returns the markdown page documenting a single rule.
+func formatRulePage(info rulesetInfo, rule documentedRule) string {
+	const rulePageTmpl = generatedMarker + `
+
+# {{.ID}}: {{.Name}}
+
+{{.Short}}
+
+| | |
+|---|---|
+| Ruleset | {{.Ruleset}} {{.Version}} |
+| Category | {{.Category}} |
+| Tags | {{.Tags}} |
+| Providers | {{.Providers}} |
+| Lints | {{.FileKinds}} |
+| Enabled by default | {{.Enabled}} |
+{{- if .Long}}
+
+{{.Long}}
+{{- end}}
+{{- if .Link}}
+
+More information: <{{.Link}}>
+{{- end}}
+{{- if .Examples}}
+
+## Examples
+{{- range .Examples}}
+
+### {{.Name}}
+
+` + "```{{.Language}}" + `
+{{.Content}}
+` + "```" + `
+{{- end}}
+{{- end}}
+
+Disable this rule with ` + "`tfvet rule disable {{.Ruleset}} {{.ID}}`" + `.
+`
+
+	type exampleData struct {
+		Name     string
+		Language string
+		Content  string
+	}
+
+	// Examples the rule ships with come before those found in its testdata.
+	examples := []exampleData{}
+	for index, example := range rule.rule.BadExamples {
+		examples = append(examples, exampleData{
+			Name:     fmt.Sprintf("Reported (%d)", index+1),
+			Language: "hcl",
+			Content:  strings.Trim(example, "\n"),
+		})
+	}
+	for index, example := range rule.rule.GoodExamples {
+		examples = append(examples, exampleData{
+			Name:     fmt.Sprintf("Compliant (%d)", index+1),
+			Language: "hcl",
+			Content:  strings.Trim(example, "\n"),
+		})
+	}
+
+	for _, example := range rule.examples {
+		language := "hcl"
+		if strings.HasSuffix(example.name, ".json") {
+			language = "json"
+		}
+
+		examples = append(examples, exampleData{
+			Name:     example.name,
+			Language: language,
+			Content:  strings.TrimRight(example.content, "\n"),
+		})
+	}
+
+	fileKinds := []string{}
+	for _, kind := range rule.rule.FileKinds {
+		fileKinds = append(fileKinds, fileKindDescriptions[kind])
+	}
+	if len(fileKinds) == 0 {
+		fileKinds = append(fileKinds, fileKindDescriptions[models.Configuration])
+	}
+
+	enabled := "no"
+	if rule.rule.Enabled {
+		enabled = "yes"
+	}
+
+	var tpl bytes.Buffer
+	t := template.Must(template.New("tmp").Parse(rulePageTmpl))
+	_ = t.Execute(&tpl, struct {
+		ID        string
+		Name      string
+		Short     string
+		Long      string
+		Link      string
+		Ruleset   string
+		Version   string
+		Category  string
+		Tags      string
+		Providers string
+		FileKinds string
+		Enabled   string
+		Examples  []exampleData
+	}{
+		ID:        rule.rule.ID,
+		Name:      rule.rule.Name,
+		Short:     rule.rule.Short,
+		Long:      strings.TrimSpace(rule.rule.Long),
+		Link:      rule.rule.Link,
+		Ruleset:   escapeTableCell(info.Name),
+		Version:   escapeTableCell(info.Version),
+		Category:  orNone(escapeTableCell(string(rule.rule.Category))),
+		Tags:      orNone(escapeTableCell(strings.Join(rule.rule.Tags, ", "))),
+		Providers: orDefault(escapeTableCell(strings.Join(rule.rule.Providers, ", ")), "all"),
+		FileKinds: strings.Join(fileKinds, ", "),
+		Enabled:   enabled,
+		Examples:  examples,
+	})
+
+	return tpl.String()
+}
+
+// fileKindDescriptions describe the kinds of files rules are run against, for use in pages.
+var fileKindDescriptions = map[models.FileKind]string{
+	models.Configuration:       "configuration files",
+	models.VariableDefinitions: "variable definitions files",
+	models.TerraformPlan:       "plans",
+}
+
+// formatIndexPage returns the markdown page listing all rules of a ruleset.
+func formatIndexPage(info rulesetInfo, rules []documentedRule) string {
+	const indexPageTmpl = generatedMarker + `
+
+# {{.Name}} ruleset
+
+Version {{.Version}}, {{len .Rules}} rule(s).
+
+| Rule | Name | Category | Description |
+|---|---|---|---|
+{{- range .Rules}}
+| [{{.ID}}]({{.ID}}.md) | {{.Name}} | {{.Category}} | {{.Short}} |
+{{- end}}
+`
+
+	type ruleData struct {
+		ID       string
+		Name     string
+		Category string
+		Short    string
+	}
+
+	data := []ruleData{}
+	for _, rule := range rules {
+		data = append(data, ruleData{
+			ID:       rule.rule.ID,
+			Name:     escapeTableCell(rule.rule.Name),
+			Category: orNone(escapeTableCell(string(rule.rule.Category))),
+			Short:    escapeTableCell(rule.rule.Short),
+		})
+	}
+
+	var tpl bytes.Buffer
+	t := template.Must(template.New("tmp").Parse(indexPageTmpl))
+	_ = t.Execute(&tpl, struct {
+		Name    string
+		Version string
+		Rules   []ruleData
+	}{
+		Name:    info.Name,
+		Version: info.Version,
+		Rules:   data,
+	})
+
+	return tpl.String()
+}
+
+// escapeTableCell makes text safe to use within a markdown table cell.
+func escapeTableCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", `\|`), "\n", " ")
+}
+
+func orNone(value string) string {
+	return orDefault(value, "none")
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+func init() {
+	cmdRulesetDocs.Flags().String("out", "docs", "directory to write the documentation to")
+
+	CmdRuleset.AddCommand(cmdRulesetDocs)
+}
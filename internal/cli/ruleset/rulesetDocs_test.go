@@ -0,0 +1,122 @@
+package ruleset
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the documentation tests")
+
+// golden compares got with the contents of the given file in testdata/docs, rewriting the file
+// instead when run with -update.
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "docs", name)
+	if *update {
+		err := ioutil.WriteFile(path, []byte(got), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != string(want) {
+		t.Errorf("%s doesn't match; run go test -update to see the difference in git.\ngot:\n%s", name, got)
+	}
+}
+
+var documentedRules = []documentedRule{
+	{
+		rule: models.Rule{
+			ID:           "EX001",
+			Name:         "No example names",
+			Short:        "Example is a poor name.",
+			Long:         "\nNames should describe what a resource is for.\n",
+			Link:         "https://example.com/EX001",
+			Enabled:      true,
+			Category:     models.CategoryStyle,
+			Tags:         []string{"naming", "a|b"},
+			Providers:    []string{"google"},
+			BadExamples:  []string{"\nresource \"a\" \"example\" {}\n"},
+			GoodExamples: []string{`resource "a" "logs" {}`},
+		},
+		examples: []example{
+			{name: "main.tf", content: "resource \"a\" \"example\" {}\n\n"},
+			{name: "main.tf.json", content: `{"resource": {}}`},
+		},
+	},
+	{
+		rule: models.Rule{
+			ID:        "EX002",
+			Name:      "Pinned | versions",
+			Short:     "Pin module\nversions.",
+			FileKinds: []models.FileKind{models.Configuration, models.VariableDefinitions},
+		},
+	},
+}
+
+func TestFormatRulePage(t *testing.T) {
+	info := rulesetInfo{Name: "example", Version: "1.0.0"}
+
+	golden(t, "EX001.md", formatRulePage(info, documentedRules[0]))
+	golden(t, "EX002.md", formatRulePage(info, documentedRules[1]))
+}
+
+func TestFormatIndexPage(t *testing.T) {
+	golden(t, "index.md", formatIndexPage(rulesetInfo{Name: "example", Version: "1.0.0"}, documentedRules))
+}
+
+func TestRemoveStalePages(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"index.md":  generatedMarker + "\n",
+		"EX001.md":  generatedMarker + "\n",
+		"EX002.md":  generatedMarker + "\n", // removed rule
+		"README.md": "# Written by hand\n",
+		"notes.txt": generatedMarker + "\n",
+	}
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.Mkdir(filepath.Join(dir, "EX003.md"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := removeStalePages(dir, map[string]bool{"index.md": true, "EX001.md": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, file := range remaining {
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+
+	// Pages written by hand, other files and directories are kept.
+	expected := []string{"EX001.md", "EX003.md", "README.md", "index.md", "notes.txt"}
+	if removed != 1 || !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected only EX002.md to be removed; removed %d and kept %v", removed, names)
+	}
+}
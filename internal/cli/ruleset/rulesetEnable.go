@@ -23,10 +23,11 @@ func runEnable(cmd *cobra.Command, args []string) error {
 		log.Fatal(err)
 	}
 
-	state, err := newState("Enabling ruleset", format)
+	state, err := newState("Enabling ruleset", format, true)
 	if err != nil {
 		return err
 	}
+	defer state.cfg.Unlock()
 
 	err = state.cfg.SetRulesetEnabled(ruleset, true)
 	if err != nil {
@@ -32,7 +32,7 @@ func runList(cmd *cobra.Command, args []string) error {
 		log.Fatal(err)
 	}
 
-	state, err := newState("", format)
+	state, err := newState("", format, false)
 	if err != nil {
 		return err
 	}
@@ -131,7 +131,7 @@ func formatRuleset(ruleset models.Ruleset) string {
 	title := fmt.Sprintf("%s %s (%s) [%d rule(s)]\n\n",
 		strings.Title(ruleset.Name), ruleset.Version, enabledStr, len(ruleset.Rules))
 
-	headers := []string{"Rule", "Name", "Description", "Enabled"}
+	headers := []string{"Rule", "Name", "Description", "Category", "Enabled"}
 	data := [][]string{}
 
 	for _, rule := range ruleset.Rules {
@@ -139,6 +139,7 @@ func formatRuleset(ruleset models.Ruleset) string {
 			rule.ID,
 			rule.Name,
 			rule.Short,
+			string(rule.Category),
 			strconv.FormatBool(rule.Enabled),
 		})
 	}
@@ -1,12 +1,22 @@
 package ruleset
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/Masterminds/semver"
+	"github.com/clintjedwards/polyfmt"
 	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+	"github.com/clintjedwards/tfvet/v2/internal/core"
+	"github.com/clintjedwards/tfvet/v2/internal/declarative"
 	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
@@ -23,13 +33,49 @@ recompilation of all rules.
 
 The resolution process is very basic and does not perform any more than a rudimentary check for diffs
 and as such, for sufficiently large repositories this might be a heavy operation.
+
+Rules that were removed from the ruleset are removed from the config. Use --dry-run to see which
+rules would be added, removed, or changed without installing the update; the new rules are built
+in a temporary directory, leaving the installed rulesets and config untouched.
+
+As with add, rules that now share an ID with a rule of another installed ruleset only cause a
+warning; IDs only have to be unique within a ruleset.
 `,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runUpdate,
+	Example: `$ tfvet ruleset update
+$ tfvet ruleset update example --dry-run`,
+}
+
+// ruleChange describes how a single rule differs between the installed and the remote version of
+// a ruleset.
+type ruleChange struct {
+	Change string        `json:"change"` // one of "added", "removed" or "changed"
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Fields []fieldChange `json:"fields,omitempty"`
+}
+
+// fieldChange is a single changed attribute of a rule.
+type fieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// changelog lists all rule changes between the installed and the remote version of a ruleset.
+type changelog struct {
+	Ruleset        string       `json:"ruleset"`
+	CurrentVersion string       `json:"current_version"`
+	RemoteVersion  string       `json:"remote_version"`
+	Changes        []ruleChange `json:"changes"`
 }
 
 func init() {
 	CmdRuleset.AddCommand(cmdRulesetUpdate)
+
+	cmdRulesetUpdate.Flags().Bool("dry-run", false,
+		"show which rules would change without installing the update")
 }
 
 func runUpdate(cmd *cobra.Command, args []string) error {
@@ -38,22 +84,33 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		log.Fatal(err)
 	}
 
-	state, err := newState("Updating ruleset", format)
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Dry runs don't change the installed rulesets or config, so they don't need to lock it.
+	state, err := newState("Updating ruleset", format, !dryRun)
 	if err != nil {
 		return err
 	}
+	defer state.cfg.Unlock()
 
 	if len(args) == 0 {
 		for _, ruleset := range state.cfg.Rulesets {
 			state.fmt.Print(fmt.Sprintf("Updating ruleset %s", ruleset.Name))
-			err := updateRuleset(state, ruleset)
+			err := updateRuleset(state, ruleset, dryRun)
 			if err != nil {
 				state.fmt.PrintErr(fmt.Sprintf("could not update ruleset %s", ruleset.Name))
 				state.fmt.Finish()
 				return err
 			}
 		}
-		state.fmt.PrintSuccess("Updated all rulesets")
+		if dryRun {
+			state.fmt.PrintSuccess("Dry run complete; no rulesets were changed")
+		} else {
+			state.fmt.PrintSuccess("Updated all rulesets")
+		}
 		state.fmt.Finish()
 		return nil
 	}
@@ -66,38 +123,61 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		state.fmt.Finish()
 		return err
 	}
-	err = updateRuleset(state, ruleset)
+	err = updateRuleset(state, ruleset, dryRun)
 	if err != nil {
 		state.fmt.PrintErr(fmt.Sprintf("could not update ruleset %s", ruleset.Name))
 		state.fmt.Finish()
 		return err
 	}
-	state.fmt.PrintSuccess("Updated all rulesets")
+	if dryRun {
+		state.fmt.PrintSuccess("Dry run complete; no rulesets were changed")
+	} else {
+		state.fmt.PrintSuccess("Updated all rulesets")
+	}
 	state.fmt.Finish()
 
 	return nil
 }
 
-func updateRuleset(s *state, ruleset models.Ruleset) error {
+// updateRuleset downloads the latest version of a ruleset and, if it is newer than the installed
+// version, rebuilds and installs it. The installed ruleset and config are only changed once all
+// rules have been built successfully.
+//
+// If dryRun is set, the changes between the installed and remote version are printed but the
+// update is not installed.
+func updateRuleset(s *state, ruleset models.Ruleset, dryRun bool) error {
+	if core.IsBuiltin(ruleset) {
+		s.fmt.PrintSuccess(fmt.Sprintf("Skipped ruleset %s; it is built into tfvet and updated along with it",
+			ruleset.Name))
+		return nil
+	}
 
 	s.fmt.Print("Retrieveing ruleset")
-	err := getRemoteRuleset(ruleset.Repository, appcfg.RepoPath(ruleset.Name))
+	tmpDownloadPath := fmt.Sprintf("%s/tfvet_%s", os.TempDir(), generateHash(ruleset.Repository))
+	err := getRemoteRuleset(ruleset.Repository, tmpDownloadPath)
 	if err != nil {
 		return err
 	}
+	defer os.RemoveAll(tmpDownloadPath) // Remove tmp dir in case we end early
 
 	s.fmt.Print("Parsing remote info")
-	info, err := getRemoteRulesetInfo(appcfg.RepoPath(ruleset.Name))
+	info, err := getRemoteRulesetInfo(tmpDownloadPath)
 	if err != nil {
 		return err
 	}
 
 	s.fmt.Print("Verifying ruleset")
-	err = verifyRuleset(appcfg.RepoPath(ruleset.Name), info)
+	err = verifyRuleset(tmpDownloadPath, info)
 	if err != nil {
 		return err
 	}
 
+	// Installed rulesets are stored under their lowercased name.
+	if !strings.EqualFold(info.Name, ruleset.Name) {
+		return fmt.Errorf("remote ruleset name %q does not match installed ruleset name %q",
+			info.Name, ruleset.Name)
+	}
+
 	newSemver, err := semver.NewVersion(info.Version)
 	if err != nil {
 		return err
@@ -116,22 +196,251 @@ func updateRuleset(s *state, ruleset models.Ruleset) error {
 	s.fmt.PrintSuccess(fmt.Sprintf("Found newer ruleset for %s (current: %s, remote: %s)",
 		ruleset.Name, ruleset.Version, info.Version))
 
+	if dryRun {
+		rules, err := s.readRemoteRules(filepath.Join(tmpDownloadPath, "rules"))
+		if err != nil {
+			return err
+		}
+
+		s.printChangelog(ruleset, info, rules)
+		return nil
+	}
+
+	s.fmt.Print("Staging ruleset")
+	err = stageRuleset(ruleset.Name, tmpDownloadPath)
+	if err != nil {
+		return err
+	}
+	defer removeStagedRuleset(ruleset.Name)
+
+	rules, err := buildAllRules(s, ruleset.Name)
+	if err != nil {
+		return err
+	}
+
+	s.printChangelog(ruleset, info, rules)
+
 	s.fmt.Print("Updating ruleset")
+	swap, err := swapRuleset(ruleset.Name)
+	if err != nil {
+		return err
+	}
+
 	err = s.cfg.UpdateRuleset(models.Ruleset{
-		Name:       info.Name,
+		Name:       ruleset.Name,
 		Version:    info.Version,
 		Repository: ruleset.Repository,
 		Enabled:    ruleset.Enabled,
-		Rules:      ruleset.Rules,
+		Rules:      appcfg.MergeRules(ruleset.Rules, rules),
 	})
 	if err != nil {
+		_ = swap.rollback()
 		return err
 	}
+	swap.commit()
+
+	return nil
+}
 
-	err = buildAllRules(s, info.Name)
+// readRemoteRules builds the rules within the given rules folder of a downloaded ruleset and
+// returns their details. Rules are built into a temporary directory, leaving the installed
+// rulesets untouched.
+func (s *state) readRemoteRules(rulesPath string) ([]models.Rule, error) {
+	fileList, err := ioutil.ReadDir(rulesPath)
 	if err != nil {
-		return err
+		errText := fmt.Sprintf("could not read rules folder: %v", err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
 	}
 
-	return nil
+	buildDir, err := ioutil.TempDir("", "tfvet-update")
+	if err != nil {
+		errText := fmt.Sprintf("could not create build directory: %v", err)
+		s.fmt.PrintErr(errText)
+		s.fmt.Finish()
+		return nil, errors.New(errText)
+	}
+	defer os.RemoveAll(buildDir)
+
+	rules := []models.Rule{}
+	for _, dirName := range ruleDirNames(fileList) {
+		dirRules, err := s.readRuleInfo(filepath.Join(rulesPath, dirName), dirName, buildDir)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, dirRules...)
+	}
+
+	return rules, nil
+}
+
+// printChangelog prints the rule changes between the installed version of a ruleset and the
+// given rules of its remote version.
+func (s *state) printChangelog(ruleset models.Ruleset, info rulesetInfo, rules []models.Rule) {
+	s.fmt.Print("Comparing rules")
+	changes := changelog{
+		Ruleset:        ruleset.Name,
+		CurrentVersion: ruleset.Version,
+		RemoteVersion:  info.Version,
+		Changes:        diffRules(installedRules(ruleset), rules),
+	}
+	s.fmt.Println(formatChangelog(changes), polyfmt.Pretty)
+	s.fmt.Println(changes, polyfmt.JSON)
+}
+
+// installedRules returns information about the installed rules of a ruleset, as reported by the
+// installed rules themselves. If a rule can't be queried the information from the config is used.
+//
+// We ask the rules rather than just using the config since the config stores the user's settings
+// and not the defaults the rule was published with.
+func installedRules(ruleset models.Ruleset) []models.Rule {
+	rules := []models.Rule{}
+
+	for _, rule := range ruleset.Rules {
+		info, err := installedRuleInfo(ruleset.Name, rule.ID)
+		if err != nil {
+			rules = append(rules, rule)
+			continue
+		}
+
+		info.ID = rule.ID
+		info.Aliases = rule.Aliases
+		rules = append(rules, info)
+	}
+
+	return rules
+}
+
+// installedRuleInfo returns information about an installed rule as published, reading declarative
+// rules from their stored definition and asking all other rules' plugins.
+func installedRuleInfo(ruleset, ruleID string) (models.Rule, error) {
+	path := appcfg.DeclarativeRulePath(ruleset, ruleID)
+	if _, err := os.Stat(path); err == nil {
+		rule, err := declarative.Load(path)
+		if err != nil {
+			return models.Rule{}, err
+		}
+
+		return *rule, nil
+	}
+
+	return getRuleInfo(ruleset, ruleID)
+}
+
+// diffRules compares the installed and the newly built rules of a ruleset.
+func diffRules(oldRules, newRules []models.Rule) []ruleChange {
+	changes := []ruleChange{}
+	matched := map[string]bool{}
+
+	for _, newRule := range newRules {
+		var oldRule *models.Rule
+		for index := range oldRules {
+			if appcfg.RuleMatches(newRule, oldRules[index].ID) {
+				oldRule = &oldRules[index]
+				break
+			}
+		}
+
+		if oldRule == nil {
+			changes = append(changes, ruleChange{
+				Change: "added",
+				ID:     newRule.ID,
+				Name:   newRule.Name,
+			})
+			continue
+		}
+		matched[oldRule.ID] = true
+
+		fields := []fieldChange{}
+		if oldRule.ID != newRule.ID {
+			fields = append(fields, fieldChange{Field: "id", Old: oldRule.ID, New: newRule.ID})
+		}
+		if oldRule.Name != newRule.Name {
+			fields = append(fields, fieldChange{Field: "name", Old: oldRule.Name, New: newRule.Name})
+		}
+		if oldRule.Short != newRule.Short {
+			fields = append(fields, fieldChange{Field: "short", Old: oldRule.Short, New: newRule.Short})
+		}
+		if oldRule.Enabled != newRule.Enabled {
+			fields = append(fields, fieldChange{
+				Field: "enabled",
+				Old:   strconv.FormatBool(oldRule.Enabled),
+				New:   strconv.FormatBool(newRule.Enabled),
+			})
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		changes = append(changes, ruleChange{
+			Change: "changed",
+			ID:     newRule.ID,
+			Name:   newRule.Name,
+			Fields: fields,
+		})
+	}
+
+	for _, oldRule := range oldRules {
+		if matched[oldRule.ID] {
+			continue
+		}
+
+		changes = append(changes, ruleChange{
+			Change: "removed",
+			ID:     oldRule.ID,
+			Name:   oldRule.Name,
+		})
+	}
+
+	return changes
+}
+
+func formatChangelog(changes changelog) string {
+	title := fmt.Sprintf("%s %s -> %s [%d rule change(s)]\n\n",
+		strings.Title(changes.Ruleset), changes.CurrentVersion, changes.RemoteVersion, len(changes.Changes))
+
+	if len(changes.Changes) == 0 {
+		return title
+	}
+
+	headers := []string{"Change", "Rule", "Name", "Details"}
+	data := [][]string{}
+
+	for _, change := range changes.Changes {
+		details := []string{}
+		for _, field := range change.Fields {
+			details = append(details, fmt.Sprintf("%s: %q -> %q", field.Field, field.Old, field.New))
+		}
+
+		data = append(data, []string{
+			change.Change,
+			change.ID,
+			change.Name,
+			strings.Join(details, "\n"),
+		})
+	}
+
+	tableString := &strings.Builder{}
+	tableString.WriteString(title)
+	table := tablewriter.NewWriter(tableString)
+
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("-")
+	table.SetHeaderLine(true)
+	table.SetBorder(false)
+	table.SetTablePadding("\t")
+	table.SetNoWhiteSpace(true)
+	table.SetHeader(headers)
+	table.AppendBulk(data)
+
+	table.Render()
+	return tableString.String()
 }
@@ -0,0 +1,126 @@
+package ruleset
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+func TestDiffRules(t *testing.T) {
+	installed := []models.Rule{
+		{ID: "EX001", Name: "First", Short: "First rule.", Enabled: true},
+		{ID: "89cd4", Name: "Second", Short: "Second rule.", Enabled: true},
+	}
+
+	tests := map[string]struct {
+		newRules []models.Rule
+		changes  []ruleChange
+	}{
+		"unchanged": {
+			newRules: installed,
+			changes:  []ruleChange{},
+		},
+		"added": {
+			newRules: append(append([]models.Rule{}, installed...), models.Rule{ID: "EX003", Name: "Third"}),
+			changes:  []ruleChange{{Change: "added", ID: "EX003", Name: "Third"}},
+		},
+		"removed": {
+			newRules: installed[:1],
+			changes:  []ruleChange{{Change: "removed", ID: "89cd4", Name: "Second"}},
+		},
+		"changed fields": {
+			newRules: []models.Rule{
+				{ID: "EX001", Name: "Renamed", Short: "Changed.", Enabled: false},
+				installed[1],
+			},
+			changes: []ruleChange{{Change: "changed", ID: "EX001", Name: "Renamed", Fields: []fieldChange{
+				{Field: "name", Old: "First", New: "Renamed"},
+				{Field: "short", Old: "First rule.", New: "Changed."},
+				{Field: "enabled", Old: "true", New: "false"},
+			}}},
+		},
+		"new id": {
+			newRules: []models.Rule{
+				installed[0],
+				{ID: "EX002", Aliases: []string{"89cd4"}, Name: "Second", Short: "Second rule.", Enabled: true},
+			},
+			changes: []ruleChange{{Change: "changed", ID: "EX002", Name: "Second", Fields: []fieldChange{
+				{Field: "id", Old: "89cd4", New: "EX002"},
+			}}},
+		},
+		"everything": {
+			newRules: []models.Rule{
+				{ID: "EX001", Name: "First", Short: "Updated.", Enabled: true},
+				{ID: "EX004", Name: "Fourth"},
+			},
+			changes: []ruleChange{
+				{Change: "changed", ID: "EX001", Name: "First", Fields: []fieldChange{
+					{Field: "short", Old: "First rule.", New: "Updated."},
+				}},
+				{Change: "added", ID: "EX004", Name: "Fourth"},
+				{Change: "removed", ID: "89cd4", Name: "Second"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		changes := diffRules(installed, test.newRules)
+		if !reflect.DeepEqual(changes, test.changes) {
+			t.Errorf("%s: expected changes %+v; got %+v", name, test.changes, changes)
+		}
+	}
+}
+
+func TestFormatChangelog(t *testing.T) {
+	tests := map[string]struct {
+		changes changelog
+		want    []string
+	}{
+		"no changes": {
+			changes: changelog{Ruleset: "example", CurrentVersion: "1.0.0", RemoteVersion: "1.0.1",
+				Changes: []ruleChange{}},
+			want: []string{"Example 1.0.0 -> 1.0.1 [0 rule change(s)]"},
+		},
+		"changes": {
+			changes: changelog{Ruleset: "example", CurrentVersion: "1.0.0", RemoteVersion: "2.0.0",
+				Changes: []ruleChange{
+					{Change: "added", ID: "EX003", Name: "Third"},
+					{Change: "removed", ID: "EX002", Name: "Second"},
+					{Change: "changed", ID: "EX001", Name: "Renamed", Fields: []fieldChange{
+						{Field: "name", Old: "First", New: "Renamed"},
+						{Field: "enabled", Old: "true", New: "false"},
+					}},
+				}},
+			want: []string{
+				"Example 1.0.0 -> 2.0.0 [3 rule change(s)]",
+				"Change Rule Name Details",
+				"added EX003 Third",
+				"removed EX002 Second",
+				`changed EX001 Renamed name: "First" -> "Renamed"`,
+				`enabled: "true" -> "false"`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		formatted := formatChangelog(test.changes)
+
+		// The table pads its columns; compare lines with their whitespace collapsed.
+		lines := map[string]bool{}
+		for _, line := range strings.Split(formatted, "\n") {
+			lines[strings.Join(strings.Fields(line), " ")] = true
+		}
+
+		for _, want := range test.want {
+			if !lines[want] {
+				t.Errorf("%s: expected the line %q in the changelog; got:\n%s", name, want, formatted)
+			}
+		}
+
+		if len(test.changes.Changes) == 0 && strings.Contains(formatted, "Change") {
+			t.Errorf("%s: expected no table without changes; got:\n%s", name, formatted)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ruleset
+
+import (
+	"errors"
+	"testing"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+func TestCheckRuleIDs(t *testing.T) {
+	tests := map[string]struct {
+		builds []*ruleBuild
+		failed []string // dirNames of builds expected to fail.
+	}{
+		"unique": {
+			builds: []*ruleBuild{
+				{dirName: "a", rules: []models.Rule{{ID: "EX001"}}},
+				{dirName: "b", rules: []models.Rule{{ID: "EX002"}, {ID: "EX003"}}},
+			},
+		},
+		"duplicate across builds": {
+			builds: []*ruleBuild{
+				{dirName: "a", rules: []models.Rule{{ID: "EX001"}}},
+				{dirName: "b", rules: []models.Rule{{ID: "ex001"}}},
+			},
+			failed: []string{"b"},
+		},
+		"duplicate within a build": {
+			builds: []*ruleBuild{
+				{dirName: "a", rules: []models.Rule{{ID: "EX001"}, {ID: "EX001"}}},
+			},
+			failed: []string{"a"},
+		},
+		"invalid": {
+			builds: []*ruleBuild{
+				{dirName: "a", rules: []models.Rule{{ID: "EX-001"}}},
+			},
+			failed: []string{"a"},
+		},
+		"reserved": {
+			builds: []*ruleBuild{
+				{dirName: "a", rules: []models.Rule{{ID: "build"}}},
+				{dirName: "b", rules: []models.Rule{{ID: "REPO"}}},
+				{dirName: "c", declarative: true, rules: []models.Rule{{ID: "Build"}}},
+			},
+			failed: []string{"a", "b", "c"},
+		},
+		"failed builds are skipped": {
+			builds: []*ruleBuild{
+				{dirName: "a", err: errors.New("does not compile"), rules: []models.Rule{{ID: "EX001"}}},
+				{dirName: "b", rules: []models.Rule{{ID: "EX001"}}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		alreadyFailed := map[string]bool{}
+		for _, build := range test.builds {
+			alreadyFailed[build.dirName] = build.err != nil
+		}
+
+		problems := checkRuleIDs(test.builds)
+		if len(problems) != len(test.failed) {
+			t.Errorf("%s: expected %d problem(s); got %v", name, len(test.failed), problems)
+		}
+
+		failed := map[string]bool{}
+		for _, dirName := range test.failed {
+			failed[dirName] = true
+		}
+
+		for _, build := range test.builds {
+			if alreadyFailed[build.dirName] {
+				continue
+			}
+
+			if (build.err != nil) != failed[build.dirName] {
+				t.Errorf("%s: expected build %s to fail: %t; got error %v", name, build.dirName,
+					failed[build.dirName], build.err)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package ruleset
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+	"github.com/otiai10/copy"
+)
+
+// Adding or updating a ruleset happens in three steps so that a failure at any point leaves the
+// previously working installation intact:
+//
+//  1. The ruleset is assembled in a staging directory and all rules are built there.
+//  2. The staged ruleset is swapped with the installed one, which is kept as a backup.
+//  3. The config is written. If this fails the backup is restored, otherwise it is removed.
+
+// stageRuleset prepares the staging directory for a ruleset from the repository downloaded to
+// repoPath. If the ruleset is already installed its compiled rules and build cache are carried over,
+// so that unchanged rules don't need to be recompiled.
+func stageRuleset(ruleset, repoPath string) error {
+	err := recoverRuleset(ruleset)
+	if err != nil {
+		return err
+	}
+
+	staged := appcfg.StagingRuleset(ruleset)
+
+	// Remove anything left over from a previously interrupted run.
+	err = os.RemoveAll(appcfg.RulesetPath(staged))
+	if err != nil {
+		return fmt.Errorf("could not clean staging directory: %w", err)
+	}
+
+	if _, err := os.Stat(appcfg.RulesetPath(ruleset)); err == nil {
+		err = copy.Copy(appcfg.RulesetPath(ruleset), appcfg.RulesetPath(staged), copy.Options{
+			// The repository is replaced by the newly downloaded one below.
+			Skip: func(src string) (bool, error) {
+				return src == appcfg.RepoPath(ruleset), nil
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not copy installed ruleset to staging directory: %w", err)
+		}
+	}
+
+	return moveRepo(staged, repoPath)
+}
+
+// removeStagedRuleset cleans up the staging directory of a ruleset.
+func removeStagedRuleset(ruleset string) {
+	_ = os.RemoveAll(appcfg.RulesetPath(appcfg.StagingRuleset(ruleset)))
+}
+
+// recoverRuleset restores the installed version of a ruleset if a previous swap was interrupted
+// before it could finish, and otherwise removes any leftover backup.
+func recoverRuleset(ruleset string) error {
+	backupPath := appcfg.RulesetPath(appcfg.BackupRuleset(ruleset))
+
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	if _, err := os.Stat(appcfg.RulesetPath(ruleset)); os.IsNotExist(err) {
+		err = os.Rename(backupPath, appcfg.RulesetPath(ruleset))
+		if err != nil {
+			return fmt.Errorf("could not restore ruleset from backup: %w", err)
+		}
+
+		return nil
+	}
+
+	err := os.RemoveAll(backupPath)
+	if err != nil {
+		return fmt.Errorf("could not remove ruleset backup: %w", err)
+	}
+
+	return nil
+}
+
+// rulesetSwap tracks a staged ruleset that has been moved into place so that the change can be
+// rolled back.
+type rulesetSwap struct {
+	ruleset   string
+	hasBackup bool // whether there was a previously installed version.
+}
+
+// swapRuleset moves the staged ruleset into place. The previously installed ruleset, if any, is kept
+// as a backup until either commit or rollback is called.
+func swapRuleset(ruleset string) (*rulesetSwap, error) {
+	swap := &rulesetSwap{ruleset: ruleset}
+
+	installedPath := appcfg.RulesetPath(ruleset)
+	backupPath := appcfg.RulesetPath(appcfg.BackupRuleset(ruleset))
+
+	if _, err := os.Stat(installedPath); err == nil {
+		err = os.Rename(installedPath, backupPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not back up installed ruleset: %w", err)
+		}
+		swap.hasBackup = true
+	}
+
+	err := os.Rename(appcfg.RulesetPath(appcfg.StagingRuleset(ruleset)), installedPath)
+	if err != nil {
+		_ = swap.rollback()
+		return nil, fmt.Errorf("could not move staged ruleset into place: %w", err)
+	}
+
+	return swap, nil
+}
+
+// rollback removes the newly installed ruleset and restores the previous one.
+func (s *rulesetSwap) rollback() error {
+	installedPath := appcfg.RulesetPath(s.ruleset)
+
+	err := os.RemoveAll(installedPath)
+	if err != nil {
+		return err
+	}
+
+	if !s.hasBackup {
+		return nil
+	}
+
+	return os.Rename(appcfg.RulesetPath(appcfg.BackupRuleset(s.ruleset)), installedPath)
+}
+
+// commit removes the backup of the previously installed ruleset.
+func (s *rulesetSwap) commit() {
+	_ = os.RemoveAll(appcfg.RulesetPath(appcfg.BackupRuleset(s.ruleset)))
+}
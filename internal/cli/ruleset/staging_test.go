@@ -0,0 +1,152 @@
+package ruleset
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+)
+
+// writeFiles writes the given files, keyed by path, creating their directories.
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	for path, content := range files {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// expectFile fails the test unless the file at path has the given content. An empty content
+// expects the file not to exist.
+func expectFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	got, err := ioutil.ReadFile(path)
+	switch {
+	case content == "" && !os.IsNotExist(err):
+		t.Errorf("expected %s not to exist", path)
+	case content != "" && string(got) != content:
+		t.Errorf("expected %s to contain %q; got %q (%v)", path, content, got, err)
+	}
+}
+
+func TestStageAndSwap(t *testing.T) {
+	useConfigPath(t)
+
+	repo := t.TempDir()
+	writeFiles(t, map[string]string{
+		appcfg.RulePath("example", "EX001"):                  "v1",
+		appcfg.BuildCacheFilePath("example"):                 "cache",
+		filepath.Join(appcfg.RepoPath("example"), "old.hcl"): "old",
+		filepath.Join(repo, "ruleset.hcl"):                   "new",
+	})
+
+	err := stageRuleset("example", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Compiled rules and the build cache are carried over; the repository is replaced.
+	staged := appcfg.StagingRuleset("example")
+	expectFile(t, appcfg.RulePath(staged, "EX001"), "v1")
+	expectFile(t, appcfg.BuildCacheFilePath(staged), "cache")
+	expectFile(t, filepath.Join(appcfg.RepoPath(staged), "ruleset.hcl"), "new")
+	expectFile(t, filepath.Join(appcfg.RepoPath(staged), "old.hcl"), "")
+
+	writeFiles(t, map[string]string{appcfg.RulePath(staged, "EX001"): "v2"})
+
+	swap, err := swapRuleset("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, appcfg.RulePath("example", "EX001"), "v2")
+	expectFile(t, appcfg.RulePath(appcfg.BackupRuleset("example"), "EX001"), "v1")
+
+	// A failed config write rolls the installation back to the previous version.
+	err = swap.rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, appcfg.RulePath("example", "EX001"), "v1")
+	expectFile(t, appcfg.RulePath(appcfg.BackupRuleset("example"), "EX001"), "")
+
+	err = stageRuleset("example", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, map[string]string{appcfg.RulePath(staged, "EX001"): "v2"})
+
+	swap, err = swapRuleset("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap.commit()
+	expectFile(t, appcfg.RulePath("example", "EX001"), "v2")
+	if _, err := os.Stat(appcfg.RulesetPath(appcfg.BackupRuleset("example"))); !os.IsNotExist(err) {
+		t.Error("expected the backup to be removed once the swap is committed")
+	}
+}
+
+func TestSwapNewRuleset(t *testing.T) {
+	useConfigPath(t)
+
+	err := stageRuleset("example", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	swap, err := swapRuleset("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rolling back a ruleset that wasn't installed before removes it.
+	err = swap.rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(appcfg.RulesetPath("example")); !os.IsNotExist(err) {
+		t.Error("expected the ruleset to be removed")
+	}
+}
+
+func TestRecoverRuleset(t *testing.T) {
+	useConfigPath(t)
+
+	backup := appcfg.BackupRuleset("example")
+
+	// Interrupted after the installed ruleset was moved aside, but before the staged one took its
+	// place: the backup is restored.
+	writeFiles(t, map[string]string{appcfg.RulePath(backup, "EX001"): "v1"})
+
+	err := recoverRuleset("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, appcfg.RulePath("example", "EX001"), "v1")
+	expectFile(t, appcfg.RulePath(backup, "EX001"), "")
+
+	// Interrupted once the staged ruleset was in place: the new version is kept.
+	writeFiles(t, map[string]string{
+		appcfg.RulePath(backup, "EX001"):    "v0",
+		appcfg.RulePath("example", "EX001"): "v1",
+	})
+
+	err = recoverRuleset("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, appcfg.RulePath("example", "EX001"), "v1")
+	if _, err := os.Stat(appcfg.RulesetPath(backup)); !os.IsNotExist(err) {
+		t.Error("expected the leftover backup to be removed")
+	}
+}
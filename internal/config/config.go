@@ -6,6 +6,8 @@ import "github.com/kelseyhightower/envconfig"
 // This makes it possible for the user to change the default path of the config files.
 type Config struct {
 	ConfigPath string `split_words:"true" default:"~/.tfvet.d"`
+	// Log is the level of rule logs written to stderr; the --log-level flag takes precedence.
+	Log string `default:"off"`
 }
 
 // FromEnv parses environment variables into the config object based on envconfig name
@@ -0,0 +1,98 @@
+// Package core is the ruleset built into tfvet.
+//
+// Its rules catch common mistakes in any terraform configuration, regardless of the providers
+// used, so that tfvet is useful before any other ruleset is added. Unlike other rulesets it isn't
+// downloaded or compiled: its rules run in-process and are updated along with tfvet itself.
+package core
+
+import (
+	"sort"
+	"strings"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+const (
+	// Name is the name of the built-in ruleset.
+	Name = "core"
+
+	// Version is the version of the built-in ruleset. It should be bumped whenever its rules
+	// change.
+	Version = "1.0.0"
+
+	// Repository stands in for the repository of the built-in ruleset, which has none.
+	Repository = "builtin"
+)
+
+// rules are the rules of the built-in ruleset.
+var rules = []*models.Rule{
+	interpolationOnly,
+	requiredProviderVersions,
+	unusedVariables,
+	duplicateLabels,
+	variableDescriptions,
+}
+
+// Rules returns the rules of the built-in ruleset, ready to be run in-process.
+func Rules() []*models.Rule {
+	return rules
+}
+
+// Ruleset returns the built-in ruleset as it is recorded in the config.
+func Ruleset() models.Ruleset {
+	infos := []models.Rule{}
+	for _, rule := range rules {
+		infos = append(infos, models.Rule{
+			ID:           rule.ID,
+			Name:         rule.Name,
+			Short:        rule.Short,
+			Long:         rule.Long,
+			Link:         rule.Link,
+			Enabled:      rule.Enabled,
+			Category:     rule.Category,
+			Tags:         rule.Tags,
+			Providers:    rule.Providers,
+			GoodExamples: rule.GoodExamples,
+			BadExamples:  rule.BadExamples,
+			FileOnly:     rule.ModuleCheck == nil && rule.ContextCheck == nil,
+		})
+	}
+
+	return models.Ruleset{
+		Name:       Name,
+		Version:    Version,
+		Repository: Repository,
+		Enabled:    true,
+		Rules:      infos,
+	}
+}
+
+// IsBuiltin returns whether the given ruleset is the built-in ruleset.
+func IsBuiltin(ruleset models.Ruleset) bool {
+	return ruleset.Name == Name && ruleset.Repository == Repository
+}
+
+// configurationFiles returns the names of the module's configuration files, sorted.
+func configurationFiles(module *models.Module) []string {
+	names := []string{}
+	for name := range module.Files() {
+		if strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// sortByLocation sorts rule errors by where they were found, for checks that find them in no
+// particular order.
+func sortByLocation(ruleErrors []models.RuleError) {
+	sort.Slice(ruleErrors, func(i, j int) bool {
+		a, b := ruleErrors[i].Location.Start, ruleErrors[j].Location.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+// lintModule runs a rule against the file main.tf of a module made up of the given files and
+// returns the lines of the errors it found.
+func lintModule(t *testing.T, rule *models.Rule, files map[string]string) []uint32 {
+	t.Helper()
+
+	return lintModuleFile(t, rule, "main.tf", files)
+}
+
+// lintModuleFile runs a rule against the named file of a module made up of the given files and
+// returns the lines of the errors it found.
+func lintModuleFile(t *testing.T, rule *models.Rule, filename string, files map[string]string) []uint32 {
+	t.Helper()
+
+	moduleFiles := map[string][]byte{}
+	for name, content := range files {
+		moduleFiles[name] = []byte(content)
+	}
+
+	response, err := rule.ExecuteRule(context.Background(), &proto.ExecuteRuleRequest{
+		HclFile:     moduleFiles[filename],
+		ModuleFiles: moduleFiles,
+		Filepath:    filename,
+		RuleId:      rule.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []uint32{}
+	for _, ruleError := range response.Errors {
+		lines = append(lines, ruleError.Location.Start.Line)
+	}
+
+	return lines
+}
+
+func expectLines(t *testing.T, rule *models.Rule, got []uint32, want ...uint32) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected errors on lines %v; got %v", rule.ID, want, got)
+	}
+
+	for index := range want {
+		if got[index] != want[index] {
+			t.Fatalf("%s: expected errors on lines %v; got %v", rule.ID, want, got)
+		}
+	}
+}
+
+func TestInterpolationOnly(t *testing.T) {
+	lines := lintModule(t, interpolationOnly, map[string]string{"main.tf": `resource "a" "b" {
+  name = "${var.name}"
+  path = "${var.root}/bin"
+  tags = { team = "${var.team}" }
+}
+`})
+
+	expectLines(t, interpolationOnly, lines, 2, 4)
+}
+
+func TestRequiredProviderVersions(t *testing.T) {
+	lines := lintModule(t, requiredProviderVersions, map[string]string{"main.tf": `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 3.0"
+    }
+    google = {
+      source = "hashicorp/google"
+    }
+    random = "~> 3.0"
+  }
+}
+`})
+
+	expectLines(t, requiredProviderVersions, lines, 7)
+}
+
+func TestUnusedVariables(t *testing.T) {
+	lines := lintModule(t, unusedVariables, map[string]string{
+		"main.tf": `variable "used" {}
+
+variable "unused" {}
+
+variable "used_elsewhere" {}
+
+resource "a" "b" {
+  name = "${var.used}-b"
+}
+`,
+		"outputs.tf": `output "o" {
+  value = var.used_elsewhere
+}
+`,
+	})
+
+	expectLines(t, unusedVariables, lines, 3)
+}
+
+func TestDuplicateLabels(t *testing.T) {
+	lines := lintModule(t, duplicateLabels, map[string]string{
+		"a.tf": `resource "aws_s3_bucket" "logs" {}
+`,
+		"main.tf": `resource "aws_s3_bucket" "logs" {}
+
+variable "region" {}
+
+variable "region" {}
+
+output "region" {}
+`,
+	})
+
+	expectLines(t, duplicateLabels, lines, 1, 5)
+
+	// Files with the same contents are told apart by name; only the later one is reported.
+	identical := map[string]string{
+		"a.tf":    `variable "region" {}` + "\n",
+		"main.tf": `variable "region" {}` + "\n",
+	}
+	expectLines(t, duplicateLabels, lintModuleFile(t, duplicateLabels, "main.tf", identical), 1)
+	expectLines(t, duplicateLabels, lintModuleFile(t, duplicateLabels, "a.tf", identical))
+}
+
+func TestVariableDescriptions(t *testing.T) {
+	lines := lintModule(t, variableDescriptions, map[string]string{"main.tf": `variable "described" {
+  description = "The region to deploy to"
+}
+
+variable "undescribed" {}
+`})
+
+	expectLines(t, variableDescriptions, lines, 5)
+}
+
+func TestExamples(t *testing.T) {
+	for _, rule := range Rules() {
+		if len(rule.GoodExamples) == 0 || len(rule.BadExamples) == 0 {
+			t.Errorf("%s: expected good and bad examples", rule.ID)
+		}
+
+		for _, example := range rule.GoodExamples {
+			if lines := lintModule(t, rule, map[string]string{"main.tf": example}); len(lines) != 0 {
+				t.Errorf("%s: expected no errors in good example; got errors on lines %v:\n%s", rule.ID, lines, example)
+			}
+		}
+
+		for _, example := range rule.BadExamples {
+			if lines := lintModule(t, rule, map[string]string{"main.tf": example}); len(lines) == 0 {
+				t.Errorf("%s: expected errors in bad example:\n%s", rule.ID, example)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+var duplicateLabels = &models.Rule{
+	ID:    "CORE004",
+	Name:  "Duplicate block labels",
+	Short: "Blocks of the same type must have unique labels within a module.",
+	Long: `
+Terraform refuses to run a module that declares the same resource, data source, module call,
+variable or output more than once, even when the declarations are in different files. Rename or
+remove all but one of them.
+`,
+	Link:     "https://www.terraform.io/docs/language/syntax/configuration.html",
+	Enabled:  true,
+	Category: models.CategoryCorrectness,
+	GoodExamples: []string{`variable "region" {}
+
+variable "zone" {}`},
+	BadExamples: []string{`variable "region" {}
+
+variable "region" {}`},
+	ContextCheck: &duplicateLabelsCheck{},
+}
+
+// uniqueBlockTypes are the types of blocks whose labels must be unique within a module.
+var uniqueBlockTypes = []string{"resource", "data", "module", "variable", "output"}
+
+type duplicateLabelsCheck struct{}
+
+// declaration is where a block was first declared.
+type declaration struct {
+	file string
+	line int
+}
+
+func (c *duplicateLabelsCheck) CheckWithContext(ctx context.Context, request *models.CheckRequest) ([]models.RuleError, error) {
+	ruleErrors := []models.RuleError{}
+
+	// The module's files are keyed by file name, so the linted file is told apart from other files
+	// of the module by its name; files can have the same contents.
+	content, module := request.Content, request.Module
+	current := filepath.Base(request.Filepath)
+
+	// The first declaration of every block within the module, in the order terraform reads the
+	// module's files.
+	first := map[string]declaration{}
+	for _, name := range configurationFiles(module) {
+		if name == current {
+			continue
+		}
+
+		for key, line := range declarations(models.ParseHCL(module.Files()[name])) {
+			if _, ok := first[key]; !ok {
+				first[key] = declaration{file: name, line: line}
+			}
+		}
+	}
+
+	seen := map[string]int{}
+	for _, blockType := range uniqueBlockTypes {
+		for _, block := range models.Blocks(models.ParseHCL(content), blockType) {
+			key := blockKey(block)
+			line := block.DefRange().Start.Line
+
+			previous, ok := first[key]
+			switch {
+			case ok && previous.file < current:
+				ruleErrors = append(ruleErrors, models.Report(block,
+					fmt.Sprintf("%s is already declared in %s on line %d", key, previous.file, previous.line)))
+			case seen[key] != 0:
+				ruleErrors = append(ruleErrors, models.Report(block,
+					fmt.Sprintf("%s is already declared on line %d", key, seen[key])))
+			default:
+				seen[key] = line
+			}
+		}
+	}
+
+	sortByLocation(ruleErrors)
+
+	return ruleErrors, nil
+}
+
+// declarations returns the line of the first declaration of every block within body whose labels
+// must be unique, keyed by the block's type and labels.
+func declarations(body *hclsyntax.Body) map[string]int {
+	lines := map[string]int{}
+
+	for _, blockType := range uniqueBlockTypes {
+		for _, block := range models.Blocks(body, blockType) {
+			if _, ok := lines[blockKey(block)]; !ok {
+				lines[blockKey(block)] = block.DefRange().Start.Line
+			}
+		}
+	}
+
+	return lines
+}
+
+// blockKey returns the way terraform refers to a block, like aws_s3_bucket.logs or var.region.
+func blockKey(block *hclsyntax.Block) string {
+	switch block.Type {
+	case "resource":
+		return strings.Join(block.Labels, ".")
+	case "variable":
+		return "var." + strings.Join(block.Labels, ".")
+	default:
+		return block.Type + "." + strings.Join(block.Labels, ".")
+	}
+}
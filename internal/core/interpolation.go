@@ -0,0 +1,63 @@
+package core
+
+import (
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+var interpolationOnly = &models.Rule{
+	ID:    "CORE001",
+	Name:  "Interpolation-only expressions",
+	Short: "Interpolation-only expressions are deprecated; use the expression directly.",
+	Long: `
+Since terraform 0.12 expressions can be used directly. Wrapping a single expression in a string,
+like "${var.name}", is deprecated and only converts the value to a string needlessly.
+
+Use var.name instead of "${var.name}".
+`,
+	Link:     "https://www.terraform.io/docs/language/expressions/strings.html#interpolation",
+	Enabled:  true,
+	Category: models.CategoryStyle,
+	GoodExamples: []string{`resource "aws_instance" "web" {
+  ami  = var.ami
+  name = "${var.prefix}-web"
+}`},
+	BadExamples: []string{`resource "aws_instance" "web" {
+  ami = "${var.ami}"
+}`},
+	Check: &interpolationOnlyCheck{},
+}
+
+type interpolationOnlyCheck struct{}
+
+func (c *interpolationOnlyCheck) Check(content []byte) ([]models.RuleError, error) {
+	ruleErrors := []models.RuleError{}
+
+	// The JSON syntax has no other way of writing expressions.
+	if models.IsJSON(content) {
+		return ruleErrors, nil
+	}
+
+	body := models.ParseHCL(content)
+
+	_ = hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+		wrap, ok := node.(*hclsyntax.TemplateWrapExpr)
+		if !ok {
+			return nil
+		}
+
+		wrapped := wrap.Wrapped.Range()
+
+		ruleError := models.Report(wrap, "Use the expression directly instead of interpolating it")
+		ruleError.Remediation = string(content[wrapped.Start.Byte:wrapped.End.Byte])
+		ruleErrors = append(ruleErrors, ruleError)
+
+		return nil
+	})
+
+	// Attributes are visited in no particular order.
+	sortByLocation(ruleErrors)
+
+	return ruleErrors, nil
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"fmt"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var requiredProviderVersions = &models.Rule{
+	ID:    "CORE002",
+	Name:  "Required provider versions",
+	Short: "Required providers should constrain the provider version.",
+	Long: `
+Without a version constraint terraform installs the newest version of a provider, which may contain
+breaking changes. Set a version constraint for every provider in required_providers so that upgrades
+happen deliberately.
+`,
+	Link:     "https://www.terraform.io/docs/language/providers/requirements.html#version-constraints",
+	Enabled:  true,
+	Category: models.CategoryCorrectness,
+	GoodExamples: []string{`terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 3.0"
+    }
+  }
+}`},
+	BadExamples: []string{`terraform {
+  required_providers {
+    aws = {
+      source = "hashicorp/aws"
+    }
+  }
+}`},
+	Check: &requiredProviderVersionsCheck{},
+}
+
+type requiredProviderVersionsCheck struct{}
+
+func (c *requiredProviderVersionsCheck) Check(content []byte) ([]models.RuleError, error) {
+	ruleErrors := []models.RuleError{}
+
+	body := models.ParseHCL(content)
+
+	for _, terraform := range models.Blocks(body, "terraform") {
+		for _, required := range models.NestedBlocks(terraform, "required_providers") {
+			for name, attribute := range required.Body.Attributes {
+				// The legacy form, a version constraint on its own, always has a version.
+				object, ok := attribute.Expr.(*hclsyntax.ObjectConsExpr)
+				if !ok || hasKey(object, "version") {
+					continue
+				}
+
+				ruleError := models.Report(attribute, fmt.Sprintf("Add a version constraint for provider %s", name))
+				ruleError.Remediation = `version = "~> <major>.<minor>"`
+				ruleErrors = append(ruleErrors, ruleError)
+			}
+		}
+	}
+
+	sortByLocation(ruleErrors)
+
+	return ruleErrors, nil
+}
+
+// hasKey returns whether an object expression sets the given key.
+func hasKey(object *hclsyntax.ObjectConsExpr, key string) bool {
+	for _, item := range object.Items {
+		value, diags := item.KeyExpr.Value(nil)
+		if diags.HasErrors() || !value.IsKnown() || value.IsNull() || !value.Type().Equals(cty.String) {
+			continue
+		}
+
+		if value.AsString() == key {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"fmt"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+var unusedVariables = &models.Rule{
+	ID:    "CORE003",
+	Name:  "Unused variables",
+	Short: "Variables that aren't used anywhere in the module should be removed.",
+	Long: `
+Input variables that no expression in the module refers to have no effect, yet callers of the
+module still have to think about them. Remove them, or use them where they were meant to be used.
+`,
+	Link:     "https://www.terraform.io/docs/language/values/variables.html",
+	Enabled:  true,
+	Category: models.CategoryStyle,
+	GoodExamples: []string{`variable "region" {}
+
+provider "aws" {
+  region = var.region
+}`},
+	BadExamples: []string{`variable "region" {}
+
+provider "aws" {
+  region = "us-east-1"
+}`},
+	ModuleCheck: &unusedVariablesCheck{},
+}
+
+type unusedVariablesCheck struct{}
+
+func (c *unusedVariablesCheck) CheckModule(content []byte, module *models.Module) ([]models.RuleError, error) {
+	ruleErrors := []models.RuleError{}
+
+	used := map[string]bool{}
+	for _, name := range configurationFiles(module) {
+		for variable := range referencedVariables(models.ParseHCL(module.Files()[name])) {
+			used[variable] = true
+		}
+	}
+
+	// The linted file might not be part of the module's files if they couldn't be read.
+	for variable := range referencedVariables(models.ParseHCL(content)) {
+		used[variable] = true
+	}
+
+	for _, variable := range models.Blocks(models.ParseHCL(content), "variable") {
+		if len(variable.Labels) == 0 || used[variable.Labels[0]] {
+			continue
+		}
+
+		ruleErrors = append(ruleErrors, models.Report(variable,
+			fmt.Sprintf("Variable %s is never used; remove it", variable.Labels[0])))
+	}
+
+	return ruleErrors, nil
+}
+
+// referencedVariables returns the names of the input variables referred to within body.
+func referencedVariables(body *hclsyntax.Body) map[string]bool {
+	variables := map[string]bool{}
+
+	_ = hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+		traversal, ok := node.(*hclsyntax.ScopeTraversalExpr)
+		if !ok || len(traversal.Traversal) < 2 || traversal.Traversal.RootName() != "var" {
+			return nil
+		}
+
+		if attribute, ok := traversal.Traversal[1].(hcl.TraverseAttr); ok {
+			variables[attribute.Name] = true
+		}
+
+		return nil
+	})
+
+	return variables
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"fmt"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+var variableDescriptions = &models.Rule{
+	ID:    "CORE005",
+	Name:  "Variable descriptions",
+	Short: "Variables should have a description.",
+	Long: `
+Descriptions tell callers of a module what a variable is for and which values it expects. They are
+shown by tooling that documents modules and in the prompt terraform shows for variables without a
+value.
+`,
+	Link:     "https://www.terraform.io/docs/language/values/variables.html#input-variable-documentation",
+	Enabled:  true,
+	Category: models.CategoryStyle,
+	GoodExamples: []string{`variable "region" {
+  description = "The region to deploy to"
+}`},
+	BadExamples: []string{`variable "region" {}`},
+	Check:       &variableDescriptionsCheck{},
+}
+
+type variableDescriptionsCheck struct{}
+
+func (c *variableDescriptionsCheck) Check(content []byte) ([]models.RuleError, error) {
+	ruleErrors := []models.RuleError{}
+
+	for _, variable := range models.Blocks(models.ParseHCL(content), "variable") {
+		if len(variable.Labels) == 0 || models.Attribute(variable, "description") != nil {
+			continue
+		}
+
+		ruleError := models.Report(variable, fmt.Sprintf("Describe what variable %s is for", variable.Labels[0]))
+		ruleError.Remediation = `description = "<what the variable is for>"`
+		ruleErrors = append(ruleErrors, ruleError)
+	}
+
+	return ruleErrors, nil
+}
@@ -0,0 +1,241 @@
+// Package declarative implements rules written in HCL instead of go.
+//
+// Many rules boil down to "blocks like these must satisfy this condition". Declarative rules
+// express exactly that without the need for a plugin: a rule directory containing a rule.hcl
+// file selects blocks by type and labels and gives a condition each selected block must meet.
+//
+//	id    = "AWS001"
+//	name  = "Tagged S3 buckets"
+//	short = "S3 buckets must be tagged with the owning team."
+//
+//	selector {
+//	  block  = "resource"
+//	  labels = ["aws_s3_bucket"]
+//	}
+//
+//	condition = can(self.tags.team)
+//	message   = "Tag the bucket with the team that owns it"
+//
+// The condition is evaluated once per selected block with the block's attributes and nested
+// blocks available as "self", the block's type and labels as "block.type" and "block.labels", and
+// the module's input variables and local values as "var" and "local". Blocks for which the
+// condition can't be determined without running terraform are skipped.
+//
+// Declarative rules are run by tfvet itself, so they don't need to be compiled and start
+// instantly.
+package declarative
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// FileName is the name of the file that defines a declarative rule within a rule directory.
+const FileName = "rule.hcl"
+
+// definition is the struct representation of a declarative rule file.
+type definition struct {
+	ID      string `hcl:"id"`
+	Name    string `hcl:"name"`
+	Short   string `hcl:"short"`
+	Long    string `hcl:"long,optional"`
+	Link    string `hcl:"link,optional"`
+	Enabled *bool  `hcl:"enabled,optional"` // defaults to true.
+
+	Category  string   `hcl:"category,optional"`
+	Tags      []string `hcl:"tags,optional"`
+	Providers []string `hcl:"providers,optional"`
+
+	GoodExamples []string `hcl:"good_examples,optional"`
+	BadExamples  []string `hcl:"bad_examples,optional"`
+
+	Selector    selector       `hcl:"selector,block"`
+	Condition   hcl.Expression `hcl:"condition"`
+	Message     string         `hcl:"message"`
+	Remediation string         `hcl:"remediation,optional"`
+}
+
+// selector picks the blocks a declarative rule checks.
+type selector struct {
+	// Block is the type of the blocks to check, "resource" if not set.
+	Block string `hcl:"block,optional"`
+	// Labels are the leading labels the blocks need to have. For resources this is usually just
+	// the resource type.
+	Labels []string `hcl:"labels,optional"`
+}
+
+// Load reads the declarative rule at the given path.
+func Load(path string) (*models.Rule, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(path, content)
+}
+
+// Parse returns the rule defined in the given declarative rule file. The returned rule can be
+// run in-process like any other rule built with the sdk.
+func Parse(filename string, content []byte) (*models.Rule, error) {
+	file, diags := hclparse.NewParser().ParseHCL(content, filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	def := definition{}
+	diags = gohcl.DecodeBody(file.Body, nil, &def)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if !models.IsValidRuleID(def.ID) {
+		return nil, fmt.Errorf("invalid rule ID %q; IDs must be between 1 and 20 alphanumeric characters", def.ID)
+	}
+
+	if def.Category != "" && !models.Category(def.Category).IsValid() {
+		return nil, fmt.Errorf("invalid category %q; accepted values are %v", def.Category, models.Categories)
+	}
+
+	if def.Selector.Block == "" {
+		def.Selector.Block = "resource"
+	}
+
+	enabled := true
+	if def.Enabled != nil {
+		enabled = *def.Enabled
+	}
+
+	return &models.Rule{
+		ID:           def.ID,
+		Name:         def.Name,
+		Short:        def.Short,
+		Long:         def.Long,
+		Link:         def.Link,
+		Enabled:      enabled,
+		Category:     models.Category(def.Category),
+		Tags:         def.Tags,
+		Providers:    def.Providers,
+		GoodExamples: def.GoodExamples,
+		BadExamples:  def.BadExamples,
+		ModuleCheck: &check{
+			selector:    def.Selector,
+			condition:   def.Condition,
+			message:     def.Message,
+			remediation: def.Remediation,
+		},
+	}, nil
+}
+
+// check evaluates the condition of a declarative rule against the blocks it selects.
+type check struct {
+	selector    selector
+	condition   hcl.Expression
+	message     string
+	remediation string
+}
+
+// functions are available to conditions on top of the ones the module provides. They allow
+// conditions to deal with attributes that aren't set.
+var functions = map[string]function.Function{
+	"can": tryfunc.CanFunc,
+	"try": tryfunc.TryFunc,
+}
+
+func (c *check) CheckModule(content []byte, module *models.Module) ([]models.RuleError, error) {
+	ruleErrors := []models.RuleError{}
+
+	// Blocks the condition can't be evaluated for don't stop the others from being checked; the
+	// problems are returned along with the lint errors found.
+	diagnostics := models.Diagnostics{}
+
+	body := models.ParseHCL(content)
+
+	for _, block := range models.Blocks(body, c.selector.Block, c.selector.Labels...) {
+		labels := []cty.Value{}
+		for _, label := range block.Labels {
+			labels = append(labels, cty.StringVal(label))
+		}
+
+		ctx := module.EvalContext().NewChild()
+		ctx.Functions = functions
+		ctx.Variables = map[string]cty.Value{
+			"self": blockValue(block, module),
+			"block": cty.ObjectVal(map[string]cty.Value{
+				"type":   cty.StringVal(block.Type),
+				"labels": cty.TupleVal(labels),
+			}),
+		}
+
+		location := models.RangeFromHCL(block.DefRange())
+
+		value, diags := c.condition.Value(ctx)
+		if diags.HasErrors() {
+			diagnostics = append(diagnostics, models.Diagnostic{
+				Severity: models.DiagnosticError,
+				Message:  fmt.Sprintf("could not evaluate condition: %s", diags.Error()),
+				Location: &location,
+			})
+			continue
+		}
+
+		if !value.IsWhollyKnown() || value.IsNull() {
+			continue
+		}
+
+		value, err := convert.Convert(value, cty.Bool)
+		if err != nil {
+			diagnostics = append(diagnostics, models.Diagnostic{
+				Severity: models.DiagnosticError,
+				Message:  fmt.Sprintf("condition must be true or false: %v", err),
+				Location: &location,
+			})
+			continue
+		}
+
+		if value.True() {
+			continue
+		}
+
+		ruleError := models.Report(block, c.message)
+		ruleError.Remediation = c.remediation
+		ruleErrors = append(ruleErrors, ruleError)
+	}
+
+	if len(diagnostics) > 0 {
+		return ruleErrors, diagnostics
+	}
+
+	return ruleErrors, nil
+}
+
+// blockValue returns the value of a block's attributes and nested blocks. Nested blocks are
+// lists keyed by their type, since a block may contain several of the same type.
+func blockValue(block *hclsyntax.Block, module *models.Module) cty.Value {
+	values := map[string]cty.Value{}
+
+	nested := map[string][]cty.Value{}
+	for _, child := range block.Body.Blocks {
+		nested[child.Type] = append(nested[child.Type], blockValue(child, module))
+	}
+
+	for blockType, blocks := range nested {
+		values[blockType] = cty.TupleVal(blocks)
+	}
+
+	// Attributes win over nested blocks of the same name; terraform doesn't allow both anyway.
+	for name, attribute := range block.Body.Attributes {
+		values[name] = module.Evaluate(attribute.Expr)
+	}
+
+	return cty.ObjectVal(values)
+}
@@ -0,0 +1,133 @@
+package declarative
+
+import (
+	"testing"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+func TestCheckModule(t *testing.T) {
+	rule, err := Parse("rule.hcl", []byte(`
+id    = "DC001"
+name  = "Tagged buckets"
+short = "S3 buckets must be tagged with the owning team."
+
+selector {
+  labels = ["aws_s3_bucket"]
+}
+
+condition   = can(self.tags.team) && length(try(self.lifecycle_rule, [])) > 0
+message     = "Tag the bucket and give it a lifecycle rule"
+remediation = "tags = { team = \"<team>\" }"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !rule.Enabled || rule.ID != "DC001" {
+		t.Fatalf("unexpected rule %+v", rule)
+	}
+
+	content := []byte(`resource "aws_s3_bucket" "good" {
+  tags = { team = var.team }
+
+  lifecycle_rule {
+    enabled = true
+  }
+}
+
+resource "aws_s3_bucket" "untagged" {
+  lifecycle_rule {
+    enabled = true
+  }
+}
+
+resource "aws_s3_bucket" "no_lifecycle" {
+  tags = { team = "web" }
+}
+
+resource "aws_instance" "ignored" {}
+
+variable "team" {}
+`)
+
+	ruleErrors, err := rule.ModuleCheck.CheckModule(content, models.NewModule(map[string][]byte{"main.tf": content}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ruleErrors) != 2 {
+		t.Fatalf("expected 2 rule errors; got %+v", ruleErrors)
+	}
+
+	if ruleErrors[0].Location.Start.Line != 9 || ruleErrors[1].Location.Start.Line != 15 {
+		t.Fatalf("unexpected locations %+v", ruleErrors)
+	}
+
+	if ruleErrors[0].Suggestion != "Tag the bucket and give it a lifecycle rule" || ruleErrors[0].Remediation == "" {
+		t.Fatalf("unexpected rule error %+v", ruleErrors[0])
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse("rule.hcl", []byte(`
+id    = "not valid!"
+name  = "Invalid"
+short = "Invalid"
+
+selector {}
+
+condition = true
+message   = "never"
+`))
+	if err == nil {
+		t.Fatal("expected an error for an invalid rule ID")
+	}
+}
+
+func TestCheckModuleConditionFails(t *testing.T) {
+	rule, err := Parse("rule.hcl", []byte(`
+id    = "DC002"
+name  = "Small clusters"
+short = "Clusters must not have more than 3 nodes."
+
+selector {
+  labels = ["aws_eks_node_group"]
+}
+
+condition = self.size <= 3
+message   = "Use at most 3 nodes"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte(`resource "aws_eks_node_group" "big" {
+  size = 5
+}
+
+resource "aws_eks_node_group" "named" {
+  size = "many"
+}
+
+resource "aws_eks_node_group" "huge" {
+  size = 10
+}
+`)
+
+	ruleErrors, err := rule.ModuleCheck.CheckModule(content, models.NewModule(map[string][]byte{"main.tf": content}))
+
+	// The block whose condition can't be evaluated doesn't hide the lint errors of the others.
+	if len(ruleErrors) != 2 || ruleErrors[0].Location.Start.Line != 1 || ruleErrors[1].Location.Start.Line != 9 {
+		t.Fatalf("expected rule errors on lines 1 and 9; got %+v", ruleErrors)
+	}
+
+	diagnostics, ok := err.(models.Diagnostics)
+	if !ok || len(diagnostics) != 1 || !diagnostics.HasErrors() {
+		t.Fatalf("expected a single error diagnostic; got %v", err)
+	}
+
+	if diagnostics[0].Location == nil || diagnostics[0].Location.Start.Line != 5 {
+		t.Fatalf("expected the diagnostic to point at the block on line 5; got %+v", diagnostics[0].Location)
+	}
+}
@@ -0,0 +1,171 @@
+package linter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	"github.com/clintjedwards/tfvet/v2/internal/utils"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// Cache stores the responses of rules on disk, so that linting files that haven't changed since
+// they were last linted doesn't have to run any rules at all.
+//
+// Responses are keyed by the hash of the request sent to the rule, which holds the contents of the
+// file (and the rest of its module, for rules that use it), the checksum of the rule binary and the
+// rule's configuration. A change to any of them results in the rule being run again.
+type Cache struct {
+	dir string
+
+	hits   int64
+	misses int64
+
+	mu           sync.Mutex
+	binaryHashes map[string]string // binary checksums computed so far, keyed by binary stamp.
+}
+
+// NewCache returns a cache that stores responses in the given directory.
+func NewCache(dir string) *Cache {
+	return &Cache{
+		dir:          dir,
+		binaryHashes: map[string]string{},
+	}
+}
+
+// Stats returns the number of rule runs that were served from the cache and the number that
+// had to be executed.
+func (c *Cache) Stats() (hits, misses int) {
+	return int(atomic.LoadInt64(&c.hits)), int(atomic.LoadInt64(&c.misses))
+}
+
+// key returns the key a rule's response to the given request is stored under.
+func (c *Cache) key(binaryPath, ruleset string, rule models.Rule, request protobuf.Message) (string, error) {
+	binaryHash, err := c.binaryHash(binaryPath)
+	if err != nil {
+		return "", err
+	}
+
+	ruleConfig, err := json.Marshal(rule)
+	if err != nil {
+		return "", err
+	}
+
+	// Maps are marshalled in a random order unless asked otherwise.
+	contents, err := protobuf.MarshalOptions{Deterministic: true}.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	requestHash := sha256.Sum256(contents)
+
+	// Different kinds of requests can marshal to the same bytes, so the kind is part of the key.
+	requestKind := request.ProtoReflect().Descriptor().FullName()
+
+	digest := sha256.New()
+	fmt.Fprintf(digest, "%s\x00%x\x00%s\x00%s\x00%s", requestKind, requestHash, binaryHash, ruleset, ruleConfig)
+
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// entryPath returns the path a cache entry is stored at. Entries are spread over subdirectories
+// to keep directories small.
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, "results", key[:2], key[2:])
+}
+
+// get returns the cached response for the given key if there is one.
+func (c *Cache) get(key string) (*proto.ExecuteRuleResponse, bool) {
+	contents, err := ioutil.ReadFile(c.entryPath(key))
+	if err != nil {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
+	response := &proto.ExecuteRuleResponse{}
+	err = protobuf.Unmarshal(contents, response)
+	if err != nil {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
+	atomic.AddInt64(&c.hits, 1)
+	return response, true
+}
+
+// set stores the response under the given key. Failing to store a response isn't fatal, the rule
+// simply has to be run again next time, so errors are ignored.
+func (c *Cache) set(key string, response *proto.ExecuteRuleResponse) {
+	contents, err := protobuf.Marshal(response)
+	if err != nil {
+		return
+	}
+
+	_ = c.write(c.entryPath(key), contents)
+}
+
+// write atomically writes a cache file so that concurrently running processes never read a
+// partially written entry.
+func (c *Cache) write(path string, contents []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(path, contents, 0644)
+}
+
+// binaryHash returns the checksum of the rule binary at the given path.
+//
+// Rule binaries are large, so hashing all of them on every run would make cache hits barely
+// cheaper than running the rules. Instead checksums are recorded by the binary's path, size and
+// modification time and only recomputed when one of those changes.
+func (c *Cache) binaryHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	stamp := fmt.Sprintf("%s\x00%d\x00%d", path, info.Size(), info.ModTime().UnixNano())
+	stampHash := sha256.Sum256([]byte(stamp))
+	stampPath := filepath.Join(c.dir, "binaries", hex.EncodeToString(stampHash[:]))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if hash, ok := c.binaryHashes[stamp]; ok {
+		return hash, nil
+	}
+
+	if hash, err := ioutil.ReadFile(stampPath); err == nil {
+		c.binaryHashes[stamp] = string(hash)
+		return string(hash), nil
+	}
+
+	binary, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer binary.Close()
+
+	digest := sha256.New()
+	_, err = io.Copy(digest, binary)
+	if err != nil {
+		return "", err
+	}
+
+	hash := hex.EncodeToString(digest.Sum(nil))
+	c.binaryHashes[stamp] = hash
+	_ = c.write(stampPath, []byte(hash))
+
+	return hash, nil
+}
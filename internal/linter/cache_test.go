@@ -0,0 +1,173 @@
+package linter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+	tfvetPlugin "github.com/clintjedwards/tfvet/v2/internal/plugin"
+	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+// cacheTestLinter returns a linter caching into a temporary config directory, with a rule binary
+// installed for the rule EX001 of the ruleset example. The binary is never started; tests run the
+// rule with their own execute function.
+func cacheTestLinter(t *testing.T) *Linter {
+	t.Helper()
+
+	dir := t.TempDir()
+	previous, ok := os.LookupEnv("TFVET_CONFIG_PATH")
+	os.Setenv("TFVET_CONFIG_PATH", dir)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("TFVET_CONFIG_PATH", previous)
+			return
+		}
+		os.Unsetenv("TFVET_CONFIG_PATH")
+	})
+
+	err := os.MkdirAll(appcfg.RulesetPath("example"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(appcfg.RulePath("example", "EX001"), []byte("binary"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := New(nil)
+	l.Cache = NewCache(filepath.Join(dir, "cache"))
+	l.plugins[binaryPath("example", "EX001")] = &rulePlugin{}
+
+	return l
+}
+
+// run runs the rule EX001 through the linter's cache, returning the response and whether the rule
+// had to be executed.
+func run(t *testing.T, l *Linter, rule models.Rule, request *proto.ExecuteRuleRequest,
+	response *proto.ExecuteRuleResponse) (*proto.ExecuteRuleResponse, bool) {
+	t.Helper()
+
+	executed := false
+	got, err := l.executeRule("example", rule, request,
+		func(context.Context, tfvetPlugin.RuleDefinition) (*proto.ExecuteRuleResponse, error) {
+			executed = true
+			return response, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return got, executed
+}
+
+func TestCacheHit(t *testing.T) {
+	l := cacheTestLinter(t)
+	rule := models.Rule{ID: "EX001"}
+	request := &proto.ExecuteRuleRequest{HclFile: []byte(`a = 1`), Filepath: "main.tf", RuleId: "EX001"}
+	response := &proto.ExecuteRuleResponse{Errors: []*proto.RuleError{{Suggestion: "found one"}}}
+
+	_, executed := run(t, l, rule, request, response)
+	if !executed {
+		t.Fatal("expected the rule to be executed on a cold cache")
+	}
+
+	got, executed := run(t, l, rule, request, nil)
+	if executed {
+		t.Fatal("expected a cached response to skip executing the rule")
+	}
+	if len(got.Errors) != 1 || got.Errors[0].Suggestion != "found one" {
+		t.Fatalf("expected the cached response; got %v", got)
+	}
+
+	if hits, misses := l.Cache.Stats(); hits != 1 || misses != 1 {
+		t.Fatalf("expected 1 hit and 1 miss; got %d and %d", hits, misses)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	l := cacheTestLinter(t)
+	rule := models.Rule{ID: "EX001"}
+	request := &proto.ExecuteRuleRequest{HclFile: []byte(`a = 1`), Filepath: "main.tf", RuleId: "EX001"}
+
+	key := func(rule models.Rule, request *proto.ExecuteRuleRequest) string {
+		t.Helper()
+
+		key, err := l.Cache.key(appcfg.RulePath("example", rule.ID), "example", rule, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	original := key(rule, request)
+	if key(rule, &proto.ExecuteRuleRequest{HclFile: []byte(`a = 1`), Filepath: "main.tf", RuleId: "EX001"}) != original {
+		t.Fatal("expected the same request to have the same key")
+	}
+
+	if key(rule, &proto.ExecuteRuleRequest{HclFile: []byte(`a = 2`), Filepath: "main.tf", RuleId: "EX001"}) == original {
+		t.Error("expected changing the file to change the key")
+	}
+
+	if key(models.Rule{ID: "EX001", Enabled: true}, request) == original {
+		t.Error("expected changing the rule's configuration to change the key")
+	}
+
+	// Binaries are stamped by their size and modification time, so rewrite it with a later one.
+	binary := appcfg.RulePath("example", "EX001")
+	err := ioutil.WriteFile(binary, []byte("rebuilt"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	err = os.Chtimes(binary, later, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key(rule, request) == original {
+		t.Error("expected changing the rule binary to change the key")
+	}
+}
+
+func TestCacheSkipsErrors(t *testing.T) {
+	l := cacheTestLinter(t)
+	rule := models.Rule{ID: "EX001"}
+	request := &proto.ExecuteRuleRequest{HclFile: []byte(`a = 1`), Filepath: "main.tf", RuleId: "EX001"}
+	response := &proto.ExecuteRuleResponse{Diagnostics: []*proto.Diagnostic{
+		{Severity: string(models.DiagnosticError), Message: "could not reach the api"},
+	}}
+
+	run(t, l, rule, request, response)
+
+	if _, executed := run(t, l, rule, request, &proto.ExecuteRuleResponse{}); !executed {
+		t.Fatal("expected a response with error diagnostics not to be cached")
+	}
+}
+
+func TestCacheCorruptEntry(t *testing.T) {
+	l := cacheTestLinter(t)
+	rule := models.Rule{ID: "EX001"}
+	request := &proto.ExecuteRuleRequest{HclFile: []byte(`a = 1`), Filepath: "main.tf", RuleId: "EX001"}
+
+	run(t, l, rule, request, &proto.ExecuteRuleResponse{})
+
+	key, err := l.Cache.key(appcfg.RulePath("example", "EX001"), "example", rule, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(l.Cache.entryPath(key), []byte("\xff\xff\xff"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, executed := run(t, l, rule, request, &proto.ExecuteRuleResponse{Errors: []*proto.RuleError{{}}})
+	if !executed || len(got.Errors) != 1 {
+		t.Fatalf("expected a corrupt entry to fall back to running the rule; got %v", got)
+	}
+}
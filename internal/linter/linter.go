@@ -0,0 +1,507 @@
+// Package linter runs the enabled rules of installed rulesets against terraform files and collects
+// the lint errors they find. It is shared by everything that needs lint results, like the lint
+// command and the language server.
+package linter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clintjedwards/tfvet/v2/internal/cli/appcfg"
+	"github.com/clintjedwards/tfvet/v2/internal/core"
+	"github.com/clintjedwards/tfvet/v2/internal/declarative"
+	tfvetPlugin "github.com/clintjedwards/tfvet/v2/internal/plugin"
+	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	"github.com/clintjedwards/tfvet/v2/internal/utils"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// Linter runs rules against terraform files.
+//
+// Rule plugins are started the first time they are needed and kept running until Close is called,
+// so linting many files, or the same file many times, only pays the plugin startup cost once.
+// Rules served by the same plugin binary share a single plugin process.
+type Linter struct {
+	// OnRule, if set, is called before a rule is run against a file. Useful for reporting progress.
+	OnRule func(ruleset string, rule models.Rule, filepath string)
+
+	// Cache, if set, is used to skip running rules against file contents they have already seen.
+	// Rules that run in-process are never cached.
+	Cache *Cache
+
+	// Logger, if set, receives the logs rules write, named after their ruleset and rule.
+	Logger hclog.Logger
+
+	// Timeout is how long a rule may run against a single file before it is cancelled. Zero means
+	// rules may run for as long as they need.
+	Timeout time.Duration
+
+	// Tags, if set, limits the rules run to those with at least one of the given tags. A rule's
+	// category counts as one of its tags.
+	Tags []string
+
+	// ExcludeCategories are the categories of rules that are not run.
+	ExcludeCategories []string
+
+	rulesets []models.Ruleset
+
+	mu sync.Mutex
+
+	// plugins are keyed by ruleset and rule ID for rules that run in-process and by the path of
+	// the plugin binary otherwise.
+	plugins map[string]*rulePlugin
+
+	// modules are the files of the modules read so far.
+	modules moduleCache
+}
+
+// rulePlugin is a rule that is ready to be executed.
+type rulePlugin struct {
+	client *plugin.Client // nil for rules that run in-process.
+	rule   tfvetPlugin.RuleDefinition
+
+	// declarative is set for in-process rules loaded from a declarative rule definition, rather
+	// than registered by the caller. They are reloaded when their ruleset changes.
+	declarative bool
+}
+
+// RuleFailure is a rule that could not be run against a file.
+type RuleFailure struct {
+	Ruleset string
+	Rule    models.Rule
+	Err     error
+}
+
+// RuleDiagnostic is a problem a rule reported running into while linting a file. Unlike a
+// RuleFailure, the lint errors the rule found are still part of the result.
+type RuleDiagnostic struct {
+	Filepath   string            `json:"filepath"`
+	Ruleset    string            `json:"ruleset"`
+	Rule       models.Rule       `json:"rule"`
+	Diagnostic models.Diagnostic `json:"diagnostic"`
+}
+
+// Result is the outcome of linting a single file.
+type Result struct {
+	Filepath string
+	// File is the parsed file, for callers that need more context around the lint errors found.
+	File        *hcl.File
+	LintErrors  []models.LintError
+	Failures    []RuleFailure
+	Diagnostics []RuleDiagnostic
+}
+
+// DefaultTimeout is how long a rule may run against a single file by default.
+const DefaultTimeout = time.Minute
+
+// New returns a linter for the given rulesets. Only enabled rulesets and rules are run.
+// The rules of the built-in ruleset are run in-process.
+func New(rulesets []models.Ruleset) *Linter {
+	l := &Linter{
+		Timeout:  DefaultTimeout,
+		rulesets: rulesets,
+		plugins:  map[string]*rulePlugin{},
+	}
+
+	for _, ruleset := range rulesets {
+		if !core.IsBuiltin(ruleset) {
+			continue
+		}
+
+		for _, rule := range core.Rules() {
+			l.plugins[pluginKey(ruleset.Name, rule.ID)] = &rulePlugin{rule: rule}
+		}
+	}
+
+	return l
+}
+
+func pluginKey(ruleset, ruleID string) string {
+	return ruleset + "/" + ruleID
+}
+
+// RegisterRule makes the linter use the given definition for a rule instead of starting the
+// rule's plugin. This allows rules to be run in-process.
+func (l *Linter) RegisterRule(ruleset, ruleID string, rule tfvetPlugin.RuleDefinition) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.plugins[pluginKey(ruleset, ruleID)] = &rulePlugin{rule: rule}
+}
+
+// SetRulesets replaces the rulesets the linter runs. Plugins of rules that are no longer present,
+// or that belong to a ruleset whose version changed, are stopped.
+func (l *Linter) SetRulesets(rulesets []models.Ruleset) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previousVersions := map[string]string{}
+	for _, ruleset := range l.rulesets {
+		previousVersions[ruleset.Name] = ruleset.Version
+	}
+
+	current := map[string]bool{}
+	for _, ruleset := range rulesets {
+		if previousVersions[ruleset.Name] != ruleset.Version {
+			continue
+		}
+
+		for _, rule := range ruleset.Rules {
+			current[binaryPath(ruleset.Name, rule.ID)] = true
+			current[pluginKey(ruleset.Name, rule.ID)] = true
+		}
+	}
+
+	for key, plugin := range l.plugins {
+		if current[key] || (plugin.client == nil && !plugin.declarative) {
+			continue
+		}
+
+		if plugin.client != nil {
+			plugin.client.Kill()
+		}
+		delete(l.plugins, key)
+	}
+
+	l.rulesets = rulesets
+}
+
+// Rulesets returns the rulesets the linter runs.
+func (l *Linter) Rulesets() []models.Ruleset {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rulesets
+}
+
+// Close stops all running rule plugins.
+func (l *Linter) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, plugin := range l.plugins {
+		if plugin.client == nil {
+			continue
+		}
+
+		plugin.client.Kill()
+		delete(l.plugins, key)
+	}
+}
+
+// LintFile runs all enabled rules against the given file contents. Filepath is used only to
+// label the results. An error is returned if the file cannot be parsed; rules that fail to run
+// are instead reported as part of the result.
+func (l *Linter) LintFile(filepath string, contents []byte) (*Result, error) {
+	file, diags := parse(filepath, contents)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	result := &Result{
+		Filepath:    filepath,
+		File:        file,
+		LintErrors:  []models.LintError{},
+		Failures:    []RuleFailure{},
+		Diagnostics: []RuleDiagnostic{},
+	}
+
+	kind := models.FileKindOf(filepath)
+
+	// The rest of the module is only read once a rule needs it, and the providers the module uses
+	// are only worked out once a rule that targets specific providers needs them.
+	var module map[string][]byte
+	getModule := func() map[string][]byte {
+		if module == nil {
+			module = l.modules.files(filepath, contents)
+		}
+		return module
+	}
+	var providers map[string]bool
+
+	// For each ruleset we need to run each one of the enabled rules against the given file.
+	for _, ruleset := range l.Rulesets() {
+		if !ruleset.Enabled {
+			continue
+		}
+
+		for _, rule := range ruleset.Rules {
+			if !rule.Enabled || !rule.AppliesTo(kind) || !l.selects(rule) {
+				continue
+			}
+
+			if len(rule.Providers) != 0 && providers == nil {
+				providers = moduleProviders(filepath, getModule(), &l.modules)
+			}
+
+			if !targets(rule, providers) {
+				continue
+			}
+
+			if l.OnRule != nil {
+				l.OnRule(ruleset.Name, rule, filepath)
+			}
+
+			// Rules that only look at the file aren't sent the module, which also keeps changes to
+			// other files of the module from invalidating their cached results.
+			request := &proto.ExecuteRuleRequest{
+				HclFile:  contents,
+				Filepath: filepath,
+				RuleId:   rule.ID,
+			}
+			if !rule.FileOnly {
+				request.ModuleFiles = getModule()
+			}
+
+			lintErrors, diagnostics, err := l.runRule(ruleset.Name, rule, filepath, request)
+			if err != nil {
+				result.Failures = append(result.Failures, RuleFailure{
+					Ruleset: ruleset.Name,
+					Rule:    rule,
+					Err:     err,
+				})
+				continue
+			}
+
+			result.LintErrors = append(result.LintErrors, lintErrors...)
+			result.Diagnostics = append(result.Diagnostics, diagnostics...)
+		}
+	}
+
+	return result, nil
+}
+
+// selects returns whether a rule is selected by the linter's tags and excluded categories.
+func (l *Linter) selects(rule models.Rule) bool {
+	for _, category := range l.ExcludeCategories {
+		if strings.EqualFold(string(rule.Category), category) {
+			return false
+		}
+	}
+
+	if len(l.Tags) == 0 {
+		return true
+	}
+
+	for _, tag := range l.Tags {
+		if rule.Category != "" && strings.EqualFold(string(rule.Category), tag) {
+			return true
+		}
+
+		for _, ruleTag := range rule.Tags {
+			if strings.EqualFold(ruleTag, tag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// parse parses file contents using the syntax matching the file name. Files ending in .tf.json use
+// Terraform's JSON syntax, everything else is parsed as native HCL syntax.
+func parse(filepath string, contents []byte) (*hcl.File, hcl.Diagnostics) {
+	if strings.HasSuffix(filepath, ".tf.json") {
+		return hclparse.NewParser().ParseJSON(contents, filepath)
+	}
+
+	return hclparse.NewParser().ParseHCL(contents, filepath)
+}
+
+// binaryPath returns the path of the plugin binary serving the given rule. Rules sharing a binary
+// with other rules are links to it, so they all resolve to the same path.
+func binaryPath(ruleset, ruleID string) string {
+	path := appcfg.RulePath(ruleset, ruleID)
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+
+	return resolved
+}
+
+// getPlugin returns a running plugin for the given rule, starting it if necessary.
+func (l *Linter) getPlugin(ruleset, ruleID string) (tfvetPlugin.RuleDefinition, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if plugin, ok := l.plugins[pluginKey(ruleset, ruleID)]; ok && plugin.client == nil {
+		return plugin.rule, nil
+	}
+
+	// Declarative rules are stored as their definition and run in-process.
+	if _, err := os.Stat(appcfg.DeclarativeRulePath(ruleset, ruleID)); err == nil {
+		rule, err := declarative.Load(appcfg.DeclarativeRulePath(ruleset, ruleID))
+		if err != nil {
+			return nil, fmt.Errorf("could not load declarative rule: %w", err)
+		}
+
+		l.plugins[pluginKey(ruleset, ruleID)] = &rulePlugin{rule: rule, declarative: true}
+		return rule, nil
+	}
+
+	key := binaryPath(ruleset, ruleID)
+
+	if plugin, ok := l.plugins[key]; ok {
+		if plugin.client == nil || !plugin.client.Exited() {
+			return plugin.rule, nil
+		}
+
+		// The plugin process died since it was last used; start a new one.
+		plugin.client.Kill()
+		delete(l.plugins, key)
+	}
+
+	client, rule, err := tfvetPlugin.Connect(key, tfvetPlugin.RulesetLogger(l.logger(), ruleset))
+	if err != nil {
+		return nil, err
+	}
+
+	l.plugins[key] = &rulePlugin{client: client, rule: rule}
+	return rule, nil
+}
+
+// isInProcess returns whether the given rule was registered to run in-process.
+func (l *Linter) isInProcess(ruleset, ruleID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	plugin, ok := l.plugins[pluginKey(ruleset, ruleID)]
+	return ok && plugin.client == nil
+}
+
+// logger returns the logger rules log to.
+func (l *Linter) logger() hclog.Logger {
+	if l.Logger == nil {
+		return hclog.NewNullLogger()
+	}
+
+	return l.Logger
+}
+
+// executeRule returns the response of a rule for the given request, either from the cache or by
+// running the rule with the given function.
+//
+// The rule is given a context that carries the linter's timeout. Rules that run in-process find
+// their logger in it.
+func (l *Linter) executeRule(ruleset string, rule models.Rule, request protobuf.Message,
+	execute func(context.Context, tfvetPlugin.RuleDefinition) (*proto.ExecuteRuleResponse, error)) (*proto.ExecuteRuleResponse, error) {
+	cacheKey := ""
+	if l.Cache != nil && !l.isInProcess(ruleset, rule.ID) {
+		// If the key can't be computed the rule can still be run, it just won't be cached.
+		key, err := l.Cache.key(appcfg.RulePath(ruleset, rule.ID), ruleset, rule, request)
+		if err == nil {
+			cacheKey = key
+		}
+	}
+
+	if cacheKey != "" {
+		if response, ok := l.Cache.get(cacheKey); ok {
+			return response, nil
+		}
+	}
+
+	plugin, err := l.getPlugin(ruleset, rule.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := hclog.WithContext(context.Background(), tfvetPlugin.RuleLogger(l.logger(), ruleset, rule.ID))
+	if l.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+		defer cancel()
+	}
+
+	// A rule that answered just as the deadline passed still finished in time; only calls that
+	// failed because of it count as timeouts.
+	response, err := execute(ctx, plugin)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("rule did not finish within %s", l.Timeout)
+		}
+		return nil, fmt.Errorf("could not execute linting rule: %w", err)
+	}
+
+	// Rules that ran into errors might succeed when run again, so their responses aren't cached.
+	if cacheKey != "" && !hasErrors(response.Diagnostics) {
+		l.Cache.set(cacheKey, response)
+	}
+
+	return response, nil
+}
+
+// hasErrors returns whether any of the diagnostics a rule reported is an error.
+func hasErrors(diagnostics []*proto.Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if models.DiagnosticSeverity(diagnostic.Severity) == models.DiagnosticError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ruleDiagnostics returns the diagnostics of a rule's response, labelled with the file they
+// were reported for.
+func ruleDiagnostics(ruleset string, rule models.Rule, filepath string, response *proto.ExecuteRuleResponse) []RuleDiagnostic {
+	diagnostics := []RuleDiagnostic{}
+	for _, diagnostic := range response.Diagnostics {
+		diagnostics = append(diagnostics, RuleDiagnostic{
+			Filepath:   filepath,
+			Ruleset:    ruleset,
+			Rule:       rule,
+			Diagnostic: *models.ProtoToDiagnostic(diagnostic),
+		})
+	}
+
+	return diagnostics
+}
+
+// runRule runs a single rule against the file contents and returns the lint errors it found,
+// along with any problems it reported running into.
+func (l *Linter) runRule(ruleset string, rule models.Rule, filepath string,
+	request *proto.ExecuteRuleRequest) ([]models.LintError, []RuleDiagnostic, error) {
+	response, err := l.executeRule(ruleset, rule, request,
+		func(ctx context.Context, plugin tfvetPlugin.RuleDefinition) (*proto.ExecuteRuleResponse, error) {
+			return plugin.ExecuteRule(ctx, request)
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lintErrors := []models.LintError{}
+	for _, ruleError := range response.Errors {
+		// Lint errors without a location apply to the file as a whole.
+		line := ""
+		if start := ruleError.GetLocation().GetStart().GetLine(); start > 0 {
+			line, _, err = utils.ReadLine(bytes.NewBuffer(request.HclFile), int(start))
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not get line from file: %w", err)
+			}
+		}
+
+		lintErrors = append(lintErrors, models.LintError{
+			Filepath: filepath,
+			Line:     line,
+			Ruleset:  ruleset,
+			Rule:     rule,
+			RuleErr:  *models.ProtoToRuleError(ruleError),
+		})
+	}
+
+	return lintErrors, ruleDiagnostics(ruleset, rule, filepath, response), nil
+}
@@ -0,0 +1,182 @@
+package linter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tfvetPlugin "github.com/clintjedwards/tfvet/v2/internal/plugin"
+	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+func TestModuleProviders(t *testing.T) {
+	providers := moduleProviders("main.tf", map[string][]byte{
+		"main.tf": []byte(`terraform {
+  required_providers {
+    random = {
+      source = "hashicorp/random"
+    }
+  }
+}
+
+provider "google" {}
+
+resource "aws_s3_bucket" "logs" {}
+
+resource "google_compute_instance" "web" {
+  provider = google-beta.west
+}
+`),
+		"data.tf.json": []byte(`{"data": {"azurerm_client_config": {"current": {}}}}`),
+		"terraform.tfvars": []byte(`region = "us-east-1"
+`),
+	}, &moduleCache{})
+
+	for _, provider := range []string{"random", "google", "aws", "google-beta", "azurerm"} {
+		if !providers[provider] {
+			t.Errorf("expected provider %s to be used; got %v", provider, providers)
+		}
+	}
+
+	if len(providers) != 5 {
+		t.Errorf("expected 5 providers; got %v", providers)
+	}
+
+	rule := models.Rule{Providers: []string{"hashicorp/aws"}}
+	if !targets(rule, providers) {
+		t.Errorf("expected rule for providers %v to target module", rule.Providers)
+	}
+
+	rule = models.Rule{Providers: []string{"kubernetes"}}
+	if targets(rule, providers) {
+		t.Errorf("expected rule for providers %v not to target module", rule.Providers)
+	}
+}
+
+func TestModuleProvidersChildModules(t *testing.T) {
+	dir := t.TempDir()
+
+	for path, contents := range map[string]string{
+		"modules/bucket/main.tf": `module "policy" {
+  source = "../policy"
+}
+
+resource "aws_s3_bucket" "logs" {}
+`,
+		"modules/policy/main.tf": `module "bucket" {
+  source = "../bucket"
+}
+
+resource "aws_iam_policy" "logs" {}
+
+data "google_client_config" "current" {}
+`,
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, "main.tf")
+	providers := moduleProviders(path, map[string][]byte{
+		"main.tf": []byte(`terraform {}
+
+module "bucket" {
+  source = "./modules/bucket"
+}
+`),
+	}, &moduleCache{})
+
+	if len(providers) != 2 || !providers["aws"] || !providers["google"] {
+		t.Errorf("expected providers of local child modules; got %v", providers)
+	}
+
+	rule := models.Rule{Providers: []string{"kubernetes"}}
+	if targets(rule, providers) {
+		t.Errorf("expected rule for providers %v not to target module", rule.Providers)
+	}
+
+	providers = moduleProviders(path, map[string][]byte{
+		"main.tf": []byte(`module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`),
+	}, &moduleCache{})
+
+	if !targets(rule, providers) {
+		t.Errorf("expected rule for providers %v to target module calling a remote module", rule.Providers)
+	}
+}
+
+func TestSelects(t *testing.T) {
+	rules := map[string]models.Rule{
+		"security": {Category: models.CategorySecurity, Tags: []string{"s3"}},
+		"style":    {Category: models.CategoryStyle, Tags: []string{"naming"}},
+		"none":     {},
+	}
+
+	tests := map[string]struct {
+		linter   *Linter
+		selected []string
+	}{
+		"all":               {linter: &Linter{}, selected: []string{"security", "style", "none"}},
+		"tag":               {linter: &Linter{Tags: []string{"S3"}}, selected: []string{"security"}},
+		"category as a tag": {linter: &Linter{Tags: []string{"style"}}, selected: []string{"style"}},
+		"excluded category": {
+			linter:   &Linter{ExcludeCategories: []string{"style"}},
+			selected: []string{"security", "none"},
+		},
+		"tag and excluded category": {
+			linter:   &Linter{Tags: []string{"s3", "naming"}, ExcludeCategories: []string{"style"}},
+			selected: []string{"security"},
+		},
+	}
+
+	for name, test := range tests {
+		selected := map[string]bool{}
+		for _, ruleName := range test.selected {
+			selected[ruleName] = true
+		}
+
+		for ruleName, rule := range rules {
+			if test.linter.selects(rule) != selected[ruleName] {
+				t.Errorf("%s: expected rule %s to be selected: %t", name, ruleName, selected[ruleName])
+			}
+		}
+	}
+}
+
+func TestExecuteRuleDeadline(t *testing.T) {
+	l := cacheTestLinter(t)
+	l.Cache = nil
+	l.Timeout = time.Millisecond
+	rule := models.Rule{ID: "EX001"}
+
+	// A response that arrives once the deadline has passed is still used.
+	response, err := l.executeRule("example", rule, &proto.ExecuteRuleRequest{},
+		func(ctx context.Context, _ tfvetPlugin.RuleDefinition) (*proto.ExecuteRuleResponse, error) {
+			<-ctx.Done()
+			return &proto.ExecuteRuleResponse{Errors: []*proto.RuleError{{}}}, nil
+		})
+	if err != nil || len(response.Errors) != 1 {
+		t.Fatalf("expected the late response to be kept; got %v, %v", response, err)
+	}
+
+	_, err = l.executeRule("example", rule, &proto.ExecuteRuleRequest{},
+		func(ctx context.Context, _ tfvetPlugin.RuleDefinition) (*proto.ExecuteRuleResponse, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	if err == nil || !strings.Contains(err.Error(), "did not finish within") {
+		t.Fatalf("expected a timeout; got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package linter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+// moduleCache keeps the files of the modules linted so far, so that linting every file of a
+// module doesn't read all of the module's files again. Files are only read again once their size
+// or modification time changes. The zero value is ready to use.
+type moduleCache struct {
+	mu      sync.Mutex
+	modules map[string]map[string]moduleFile // keyed by directory and file name.
+}
+
+// moduleFile is a file of a module as it was last read.
+type moduleFile struct {
+	size     int64
+	modTime  int64
+	contents []byte
+}
+
+// files returns the files of the module the given file belongs to, keyed by file name. The
+// module is made up of the files in the same directory, with the given contents standing in for
+// the file itself. Files that can't be read are left out.
+func (c *moduleCache) files(path string, contents []byte) map[string][]byte {
+	files := c.dirFiles(filepath.Dir(path))
+	files[filepath.Base(path)] = contents
+
+	return files
+}
+
+// dirFiles returns the files of the module in the given directory, keyed by file name. Files that
+// can't be read are left out.
+func (c *moduleCache) dirFiles(dir string) map[string][]byte {
+	files := map[string][]byte{}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return files
+	}
+
+	c.mu.Lock()
+	previous := c.modules[dir]
+	c.mu.Unlock()
+
+	current := map[string]moduleFile{}
+	for _, entry := range entries {
+		if entry.IsDir() || !models.IsModuleFile(entry.Name()) {
+			continue
+		}
+
+		file, ok := previous[entry.Name()]
+		if !ok || file.size != entry.Size() || file.modTime != entry.ModTime().UnixNano() {
+			fileContents, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+
+			file = moduleFile{size: entry.Size(), modTime: entry.ModTime().UnixNano(), contents: fileContents}
+		}
+		current[entry.Name()] = file
+		files[entry.Name()] = file.contents
+	}
+
+	c.mu.Lock()
+	if c.modules == nil {
+		c.modules = map[string]map[string]moduleFile{}
+	}
+	c.modules[dir] = current
+	c.mu.Unlock()
+
+	return files
+}
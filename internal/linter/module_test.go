@@ -0,0 +1,55 @@
+package linter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestModuleCache(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(path, modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+	write("main.tf", `resource "a" "b" {}`, modTime)
+	write("vars.tf", `variable "x" {}`, modTime)
+	write("prod.tfvars", `x = 1`, modTime)
+
+	var modules moduleCache
+
+	files := modules.files(filepath.Join(dir, "main.tf"), []byte("unsaved"))
+	if len(files) != 2 || string(files["main.tf"]) != "unsaved" || string(files["vars.tf"]) != `variable "x" {}` {
+		t.Fatalf("unexpected module: %q", files)
+	}
+
+	// Files that didn't change aren't read again, so changing a file behind the cache's back without
+	// touching its size or modification time goes unnoticed.
+	write("vars.tf", `variable "y" {}`, modTime)
+	files = modules.files(filepath.Join(dir, "vars.tf"), []byte(`variable "y" {}`))
+	if string(files["vars.tf"]) != `variable "y" {}` || string(files["main.tf"]) != `resource "a" "b" {}` {
+		t.Fatalf("unexpected module: %q", files)
+	}
+	files = modules.files(filepath.Join(dir, "main.tf"), []byte(`resource "a" "b" {}`))
+	if string(files["vars.tf"]) != `variable "x" {}` {
+		t.Fatalf("expected vars.tf to be served from the cache; got %q", files["vars.tf"])
+	}
+
+	write("vars.tf", `variable "y" {}`, modTime.Add(time.Minute))
+	os.Remove(filepath.Join(dir, "main.tf"))
+	files = modules.files(filepath.Join(dir, "prod.tfvars"), []byte(`x = 1`))
+	if len(files) != 2 || string(files["vars.tf"]) != `variable "y" {}` {
+		t.Fatalf("expected changed files to be read again and removed ones dropped; got %q", files)
+	}
+}
@@ -0,0 +1,308 @@
+package linter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tfvetPlugin "github.com/clintjedwards/tfvet/v2/internal/plugin"
+	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	"github.com/clintjedwards/tfvet/v2/internal/utils"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// LintPlan runs all enabled plan rules against the given terraform plan, in the JSON format
+// produced by `terraform show -json`.
+//
+// configDir is the directory of the configuration the plan was created from. Lint errors are
+// pointed at the configuration of the resource they were found in and grouped into one result per
+// configuration file. Lint errors whose resource can't be found point at the plan itself, with the
+// resource's address standing in for the line.
+func (l *Linter) LintPlan(planPath string, contents []byte, configDir string) ([]*Result, error) {
+	plan, err := models.ParsePlan(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := newPlanSources(contents, configDir)
+	providers := planProviders(plan)
+
+	planResult := &Result{
+		Filepath:    planPath,
+		LintErrors:  []models.LintError{},
+		Failures:    []RuleFailure{},
+		Diagnostics: []RuleDiagnostic{},
+	}
+	results := map[string]*Result{}
+
+	for _, ruleset := range l.Rulesets() {
+		if !ruleset.Enabled {
+			continue
+		}
+
+		for _, rule := range ruleset.Rules {
+			if !rule.Enabled || !rule.AppliesTo(models.TerraformPlan) || !l.selects(rule) ||
+				!targets(rule, providers) {
+				continue
+			}
+
+			if l.OnRule != nil {
+				l.OnRule(ruleset.Name, rule, planPath)
+			}
+
+			request := &proto.ExecutePlanRuleRequest{Plan: contents, RuleId: rule.ID}
+			response, err := l.executeRule(ruleset.Name, rule, request,
+				func(ctx context.Context, plugin tfvetPlugin.RuleDefinition) (*proto.ExecuteRuleResponse, error) {
+					return plugin.ExecutePlanRule(ctx, request)
+				})
+			if err != nil {
+				planResult.Failures = append(planResult.Failures, RuleFailure{
+					Ruleset: ruleset.Name,
+					Rule:    rule,
+					Err:     err,
+				})
+				continue
+			}
+
+			planResult.Diagnostics = append(planResult.Diagnostics,
+				ruleDiagnostics(ruleset.Name, rule, planPath, response)...)
+
+			for _, ruleError := range response.Errors {
+				lintError := models.LintError{
+					Filepath: planPath,
+					Line:     ruleError.Address,
+					Ruleset:  ruleset.Name,
+					Rule:     rule,
+					RuleErr:  *models.ProtoToRuleError(ruleError),
+				}
+
+				path, file, srcRange, ok := sources.find(ruleError.Address)
+				if !ok {
+					planResult.LintErrors = append(planResult.LintErrors, lintError)
+					continue
+				}
+
+				line, _, err := utils.ReadLine(bytes.NewBuffer(sources.contents[path]), srcRange.Start.Line)
+				if err != nil {
+					return nil, fmt.Errorf("could not get line from file: %w", err)
+				}
+
+				lintError.Filepath = path
+				lintError.Line = line
+				lintError.RuleErr.Location = models.Range{
+					Start: models.Position{Line: uint32(srcRange.Start.Line), Column: uint32(srcRange.Start.Column)},
+					End:   models.Position{Line: uint32(srcRange.End.Line), Column: uint32(srcRange.End.Column)},
+				}
+
+				if _, ok := results[path]; !ok {
+					results[path] = &Result{
+						Filepath:    path,
+						File:        file,
+						LintErrors:  []models.LintError{},
+						Failures:    []RuleFailure{},
+						Diagnostics: []RuleDiagnostic{},
+					}
+				}
+				results[path].LintErrors = append(results[path].LintErrors, lintError)
+			}
+		}
+	}
+
+	paths := []string{}
+	for path := range results {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	sorted := []*Result{planResult}
+	for _, path := range paths {
+		sorted = append(sorted, results[path])
+	}
+
+	return sorted, nil
+}
+
+// stripInstanceKeys removes the count and for_each keys of module and resource instances from an
+// address, like [0] or ["eu-west-1"]. String keys are quoted and may contain anything, including
+// dots, brackets and escaped quotes.
+func stripInstanceKeys(address string) string {
+	var stripped strings.Builder
+
+	depth := 0
+	quoted := false
+	for index := 0; index < len(address); index++ {
+		char := address[index]
+
+		switch {
+		case quoted && char == '\\':
+			index++ // the escaped character can't end the string.
+		case char == '"' && depth > 0:
+			quoted = !quoted
+		case quoted:
+		case char == '[':
+			depth++
+		case char == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			stripped.WriteByte(char)
+		}
+	}
+
+	return stripped.String()
+}
+
+// planSources finds the configuration of the resources in a plan.
+type planSources struct {
+	moduleDirs map[string]string // directories of modules, keyed by module path like "network.subnets".
+
+	files    map[string]*hcl.File // parsed configuration files, keyed by path.
+	contents map[string][]byte    // contents of configuration files, keyed by path.
+}
+
+// newPlanSources returns the sources of the configuration the plan was created from.
+//
+// Module directories are read from the module manifest written by `terraform init` when there is
+// one. Otherwise the sources of module calls recorded in the plan are used, which only works for
+// modules stored locally.
+func newPlanSources(plan []byte, configDir string) *planSources {
+	sources := &planSources{
+		moduleDirs: map[string]string{"": configDir},
+		files:      map[string]*hcl.File{},
+		contents:   map[string][]byte{},
+	}
+
+	raw := struct {
+		Configuration struct {
+			RootModule planConfigModule `json:"root_module"`
+		} `json:"configuration"`
+	}{}
+	_ = json.Unmarshal(plan, &raw)
+	sources.addModuleCalls("", configDir, raw.Configuration.RootModule)
+
+	manifest := struct {
+		Modules []struct {
+			Key string `json:"Key"`
+			Dir string `json:"Dir"`
+		} `json:"Modules"`
+	}{}
+
+	contents, err := ioutil.ReadFile(filepath.Join(configDir, ".terraform", "modules", "modules.json"))
+	if err == nil && json.Unmarshal(contents, &manifest) == nil {
+		for _, module := range manifest.Modules {
+			sources.moduleDirs[module.Key] = filepath.Join(configDir, module.Dir)
+		}
+	}
+
+	return sources
+}
+
+// planConfigModule is a module within the configuration section of a plan.
+type planConfigModule struct {
+	ModuleCalls map[string]struct {
+		Source string           `json:"source"`
+		Module planConfigModule `json:"module"`
+	} `json:"module_calls"`
+}
+
+// addModuleCalls records the directories of the locally stored modules called by the given module.
+func (s *planSources) addModuleCalls(path, dir string, module planConfigModule) {
+	for name, call := range module.ModuleCalls {
+		if !strings.HasPrefix(call.Source, "./") && !strings.HasPrefix(call.Source, "../") {
+			continue
+		}
+
+		callPath := name
+		if path != "" {
+			callPath = path + "." + name
+		}
+
+		callDir := filepath.Join(dir, call.Source)
+		s.moduleDirs[callPath] = callDir
+		s.addModuleCalls(callPath, callDir, call.Module)
+	}
+}
+
+// find returns the file and range of the block that declares the resource with the given address.
+func (s *planSources) find(address string) (string, *hcl.File, hcl.Range, bool) {
+	parts := strings.Split(stripInstanceKeys(address), ".")
+
+	modules := []string{}
+	for len(parts) >= 2 && parts[0] == "module" {
+		modules = append(modules, parts[1])
+		parts = parts[2:]
+	}
+
+	blockType := "resource"
+	if len(parts) > 0 && parts[0] == "data" {
+		blockType = "data"
+		parts = parts[1:]
+	}
+
+	if len(parts) != 2 {
+		return "", nil, hcl.Range{}, false
+	}
+
+	dir, ok := s.moduleDirs[strings.Join(modules, ".")]
+	if !ok {
+		return "", nil, hcl.Range{}, false
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", nil, hcl.Range{}, false
+	}
+
+	schema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: blockType, LabelNames: []string{"type", "name"}}},
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		file := s.file(path)
+		if file == nil {
+			continue
+		}
+
+		content, _, _ := file.Body.PartialContent(schema)
+		for _, block := range content.Blocks {
+			if block.Labels[0] == parts[0] && block.Labels[1] == parts[1] {
+				return path, file, block.DefRange, true
+			}
+		}
+	}
+
+	return "", nil, hcl.Range{}, false
+}
+
+// file returns the parsed configuration file at the given path, or nil if it can't be parsed.
+func (s *planSources) file(path string) *hcl.File {
+	if file, ok := s.files[path]; ok {
+		return file
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		s.files[path] = nil
+		return nil
+	}
+
+	file, diags := parse(path, contents)
+	if diags.HasErrors() {
+		file = nil
+	}
+
+	s.files[path] = file
+	s.contents[path] = contents
+	return file
+}
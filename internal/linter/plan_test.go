@@ -0,0 +1,94 @@
+package linter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanSourcesFind(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.tf": `resource "aws_s3_bucket" "logs" {}
+
+data "aws_caller_identity" "current" {}
+`,
+		"modules/network/main.tf": `resource "aws_vpc" "main" {}
+`,
+		"modules/network/subnets/subnets.tf": `
+resource "aws_subnet" "private" {}
+`,
+		".terraform/modules/remote/main.tf": `resource "aws_instance" "web" {}
+`,
+		".terraform/modules/remote/inner/main.tf": `
+
+resource "aws_instance" "web" {}
+`,
+		".terraform/modules/modules.json": `{"Modules": [
+  {"Key": "", "Dir": "."},
+  {"Key": "remote", "Dir": ".terraform/modules/remote"},
+  {"Key": "remote.inner", "Dir": ".terraform/modules/remote/inner"}
+]}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan := []byte(`{"configuration": {"root_module": {"module_calls": {
+  "network": {"source": "./modules/network", "module": {"module_calls": {
+    "subnets": {"source": "./subnets"}
+  }}},
+  "registry": {"source": "terraform-aws-modules/vpc/aws"}
+}}}}`)
+
+	sources := newPlanSources(plan, dir)
+
+	tests := map[string]struct {
+		file string // empty if the resource shouldn't be found.
+		line int
+	}{
+		"aws_s3_bucket.logs":                                {file: "main.tf", line: 1},
+		"aws_s3_bucket.logs[0]":                             {file: "main.tf", line: 1},
+		"data.aws_caller_identity.current":                  {file: "main.tf", line: 3},
+		"module.network.aws_vpc.main":                       {file: "modules/network/main.tf", line: 1},
+		"module.network.module.subnets.aws_subnet.private":  {file: "modules/network/subnets/subnets.tf", line: 2},
+		"module.remote.aws_instance.web":                    {file: ".terraform/modules/remote/main.tf", line: 1},
+		"module.remote.module.inner.aws_instance.web":       {file: ".terraform/modules/remote/inner/main.tf", line: 3},
+		`module.network["eu.west"].aws_vpc.main["a]b.c"]`:   {file: "modules/network/main.tf", line: 1},
+		`module.network[1].aws_vpc.main["say \"hi\" [x]."]`: {file: "modules/network/main.tf", line: 1},
+		"aws_s3_bucket.missing":                             {},
+		"data.aws_s3_bucket.logs":                           {},
+		"module.registry.aws_vpc.this":                      {},
+		"module.network.module.missing.aws_subnet.private":  {},
+		"module.network":                                    {},
+	}
+
+	for address, test := range tests {
+		path, file, srcRange, ok := sources.find(address)
+		if test.file == "" {
+			if ok {
+				t.Errorf("%s: expected the resource not to be found; got %s", address, path)
+			}
+			continue
+		}
+
+		if !ok {
+			t.Errorf("%s: expected the resource to be found in %s", address, test.file)
+			continue
+		}
+
+		if path != filepath.Join(dir, test.file) || file == nil || srcRange.Start.Line != test.line {
+			t.Errorf("%s: expected %s on line %d; got %s on line %d", address, test.file, test.line,
+				path, srcRange.Start.Line)
+		}
+	}
+}
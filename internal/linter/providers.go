@@ -0,0 +1,149 @@
+package linter
+
+import (
+	"path/filepath"
+	"strings"
+
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// providerName returns the local name of a provider, given either as its name or as its source
+// address. For example "registry.terraform.io/hashicorp/aws" and "aws" are both "aws".
+func providerName(provider string) string {
+	provider = strings.ToLower(provider)
+	return provider[strings.LastIndex(provider, "/")+1:]
+}
+
+// resourceProvider returns the provider terraform implies for a resource type, which is the part
+// of the type up to the first underscore.
+func resourceProvider(resourceType string) string {
+	return providerName(strings.SplitN(resourceType, "_", 2)[0])
+}
+
+// anyProvider is recorded as a provider of modules whose providers can't all be known, so that
+// rules for every provider target them.
+const anyProvider = "*"
+
+// moduleProviders returns the names of the providers used by the configuration files of the module
+// the file at path belongs to: those of its resources and data sources, configured in provider blocks or listed as
+// required providers. Root modules often only call other modules, so the providers of local child
+// modules are included. Remote child modules can't be read, so calling one makes the module use
+// any provider.
+func moduleProviders(path string, files map[string][]byte, modules *moduleCache) map[string]bool {
+	providers := map[string]bool{}
+	addModuleProviders(providers, filepath.Dir(path), files, modules, map[string]bool{})
+
+	return providers
+}
+
+// addModuleProviders adds the providers used by the module in dir, and those of its local child
+// modules not visited yet, to providers.
+func addModuleProviders(providers map[string]bool, dir string, files map[string][]byte,
+	modules *moduleCache, visited map[string]bool) {
+	visited[dir] = true
+
+	for name, content := range files {
+		if models.FileKindOf(name) != models.Configuration {
+			continue
+		}
+
+		body := models.ParseHCL(content)
+
+		for _, blockType := range []string{"resource", "data"} {
+			for _, block := range models.Blocks(body, blockType) {
+				if len(block.Labels) > 0 {
+					providers[resourceProvider(block.Labels[0])] = true
+				}
+
+				// Resources can pick a provider other than the implied one, like google-beta.
+				if attribute := models.Attribute(block, "provider"); attribute != nil {
+					traversal, diags := hcl.AbsTraversalForExpr(attribute.Expr)
+					if !diags.HasErrors() {
+						providers[providerName(traversal.RootName())] = true
+					}
+				}
+			}
+		}
+
+		for _, block := range models.Blocks(body, "provider") {
+			if len(block.Labels) > 0 {
+				providers[providerName(block.Labels[0])] = true
+			}
+		}
+
+		for _, terraform := range models.Blocks(body, "terraform") {
+			for _, required := range models.NestedBlocks(terraform, "required_providers") {
+				for name := range required.Body.Attributes {
+					providers[providerName(name)] = true
+				}
+			}
+		}
+
+		for _, module := range models.Blocks(body, "module") {
+			source, ok := localModuleSource(module)
+			if !ok {
+				providers[anyProvider] = true
+				continue
+			}
+
+			child := filepath.Join(dir, source)
+			if !visited[child] {
+				addModuleProviders(providers, child, modules.dirFiles(child), modules, visited)
+			}
+		}
+	}
+}
+
+// localModuleSource returns the source of a module call if it's a local path, which terraform
+// requires to start with "./" or "../".
+func localModuleSource(module *hclsyntax.Block) (string, bool) {
+	attribute := models.Attribute(module, "source")
+	if attribute == nil {
+		return "", false
+	}
+
+	value, diags := attribute.Expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return "", false
+	}
+
+	source := value.AsString()
+	if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+		return "", false
+	}
+
+	return source, true
+}
+
+// planProviders returns the names of the providers of all resources within a plan.
+func planProviders(plan *models.Plan) map[string]bool {
+	providers := map[string]bool{}
+
+	for _, resource := range plan.Resources {
+		providers[resourceProvider(resource.Type)] = true
+		if resource.ProviderName != "" {
+			providers[providerName(resource.ProviderName)] = true
+		}
+	}
+
+	return providers
+}
+
+// targets returns whether a rule targets any of the given providers. Rules that don't name any
+// providers target all of them.
+func targets(rule models.Rule, providers map[string]bool) bool {
+	if len(rule.Providers) == 0 || providers[anyProvider] {
+		return true
+	}
+
+	for _, provider := range rule.Providers {
+		if providers[providerName(provider)] {
+			return true
+		}
+	}
+
+	return false
+}
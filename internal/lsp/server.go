@@ -0,0 +1,465 @@
+// Package lsp implements a language server that surfaces tfvet lint errors in editors.
+//
+// The server speaks the Language Server Protocol over a single connection (usually stdio). It lints
+// documents as they are opened and changed, publishing lint errors as diagnostics, offers rule
+// remediations as code actions and shows rule documentation on hover.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// diagnosticSource is the name shown by editors as the origin of diagnostics.
+const diagnosticSource = "tfvet"
+
+// Server is a tfvet language server.
+type Server struct {
+	linter  *linter.Linter
+	version string
+	conn    *conn
+
+	mu        sync.Mutex
+	documents map[string]*document // keyed by uri.
+	shutdown  bool
+}
+
+// document is an open text document and the results of last linting it.
+type document struct {
+	version    int
+	lintErrors []models.LintError
+}
+
+// NewServer returns a language server that lints documents with the given linter. The linter's
+// rule plugins are kept running for the lifetime of the server, so it's up to the caller to close
+// the linter once Run returns.
+func NewServer(linter *linter.Linter, version string) *Server {
+	return &Server{
+		linter:    linter,
+		version:   version,
+		documents: map[string]*document{},
+	}
+}
+
+// Run serves requests read from r, writing responses and notifications to w. It returns once the
+// client sends the exit notification or the connection is closed.
+func (s *Server) Run(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+
+	for {
+		msg, err := s.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			var respErr *responseError
+			if errors.As(err, &respErr) {
+				_ = s.conn.reply(nil, nil, respErr)
+				continue
+			}
+
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, respErr := s.handle(msg)
+
+		// Notifications don't get a response.
+		if msg.ID == nil {
+			continue
+		}
+
+		err = s.conn.reply(msg.ID, result, respErr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a single request or notification to its handler.
+func (s *Server) handle(msg *message) (interface{}, *responseError) {
+	s.mu.Lock()
+	shutdown := s.shutdown
+	s.mu.Unlock()
+
+	if shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   textDocumentSyncFull,
+				HoverProvider:      true,
+				CodeActionProvider: true,
+			},
+			ServerInfo: serverInfo{
+				Name:    "tfvet",
+				Version: s.version,
+			},
+		}, nil
+
+	case "initialized":
+		return nil, nil
+
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		s.mu.Unlock()
+		return nil, nil
+
+	case "textDocument/didOpen":
+		params := didOpenTextDocumentParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		s.lintDocument(params.TextDocument.URI, params.TextDocument.Version, params.TextDocument.Text)
+		return nil, nil
+
+	case "textDocument/didChange":
+		params := didChangeTextDocumentParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		// We only advertise full document sync so the last change holds the entire document.
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		s.lintDocument(params.TextDocument.URI, params.TextDocument.Version, text)
+		return nil, nil
+
+	case "textDocument/didClose":
+		params := didCloseTextDocumentParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		s.mu.Lock()
+		delete(s.documents, params.TextDocument.URI)
+		s.mu.Unlock()
+
+		s.publish(params.TextDocument.URI, 0, []diagnostic{})
+		return nil, nil
+
+	case "textDocument/hover":
+		params := textDocumentPositionParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		return s.hover(params), nil
+
+	case "textDocument/codeAction":
+		params := codeActionParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		return s.codeActions(params), nil
+
+	default:
+		if msg.ID == nil {
+			// Unknown notifications are safe to ignore.
+			return nil, nil
+		}
+
+		return nil, &responseError{
+			Code:    codeMethodNotFound,
+			Message: fmt.Sprintf("method %q not supported", msg.Method),
+		}
+	}
+}
+
+// lintDocument lints the given document text and publishes the results as diagnostics.
+func (s *Server) lintDocument(uri string, version int, text string) {
+	doc := &document{version: version}
+	diagnostics := []diagnostic{}
+
+	result, err := s.linter.LintFile(uriToPath(uri), []byte(text))
+	if err != nil {
+		diagnostics = append(diagnostics, parseErrorDiagnostics(err)...)
+	} else {
+		doc.lintErrors = result.LintErrors
+
+		for _, lintError := range result.LintErrors {
+			diagnostics = append(diagnostics, lintErrorDiagnostic(lintError))
+		}
+
+		// Rules that fail to run have no location in the file, so the best we can do is show them at
+		// the top of the document.
+		for _, failure := range result.Failures {
+			diagnostics = append(diagnostics, diagnostic{
+				Severity: severityError,
+				Code:     failure.Rule.ID,
+				Source:   diagnosticSource,
+				Message: fmt.Sprintf("[%s] rule %q failed to run: %v",
+					failure.Ruleset, failure.Rule.Name, failure.Err),
+			})
+		}
+
+		for _, ruleDiagnostic := range result.Diagnostics {
+			diagnostics = append(diagnostics, ruleDiagnosticDiagnostic(ruleDiagnostic))
+		}
+	}
+
+	s.mu.Lock()
+	s.documents[uri] = doc
+	s.mu.Unlock()
+
+	s.publish(uri, version, diagnostics)
+}
+
+func (s *Server) publish(uri string, version int, diagnostics []diagnostic) {
+	_ = s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Version:     version,
+		Diagnostics: diagnostics,
+	})
+}
+
+// lintErrors returns the lint errors last found in the given document.
+func (s *Server) lintErrors(uri string) []models.LintError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil
+	}
+
+	return doc.lintErrors
+}
+
+// hover returns the documentation of every rule with a lint error at the given position.
+// Lint errors without a location apply to the file as a whole and aren't at any position.
+func (s *Server) hover(params textDocumentPositionParams) *hover {
+	sections := []string{}
+
+	for _, lintError := range s.lintErrors(params.TextDocument.URI) {
+		if lintError.RuleErr.Location.Start.Line == 0 {
+			continue
+		}
+
+		if !toLSPRange(lintError.RuleErr.Location).contains(params.Position) {
+			continue
+		}
+
+		sections = append(sections, formatRuleDocs(lintError))
+	}
+
+	if len(sections) == 0 {
+		return nil
+	}
+
+	return &hover{
+		Contents: markupContent{
+			Kind:  "markdown",
+			Value: strings.Join(sections, "\n\n---\n\n"),
+		},
+	}
+}
+
+// codeActions returns a quick fix for every lint error in the given range that has a remediation.
+//
+// Remediations replace the lines the lint error spans, matching how they are displayed by
+// the lint command. Lint errors without a location span no lines, so they get no quick fix.
+func (s *Server) codeActions(params codeActionParams) []codeAction {
+	actions := []codeAction{}
+
+	for _, lintError := range s.lintErrors(params.TextDocument.URI) {
+		if lintError.RuleErr.Remediation == "" || lintError.RuleErr.Location.Start.Line == 0 {
+			continue
+		}
+
+		errRange := toLSPRange(lintError.RuleErr.Location)
+		if !errRange.overlaps(params.Range) {
+			continue
+		}
+
+		newText := lintError.RuleErr.Remediation
+		if !strings.HasSuffix(newText, "\n") {
+			newText += "\n"
+		}
+
+		actions = append(actions, codeAction{
+			Title:       fmt.Sprintf("Apply remediation for %s: %s", lintError.Rule.ID, lintError.Rule.Name),
+			Kind:        "quickfix",
+			Diagnostics: []diagnostic{lintErrorDiagnostic(lintError)},
+			Edit: workspaceEdit{
+				Changes: map[string][]textEdit{
+					params.TextDocument.URI: {{
+						Range: lspRange{
+							Start: position{Line: errRange.Start.Line},
+							End:   position{Line: errRange.End.Line + 1},
+						},
+						NewText: newText,
+					}},
+				},
+			},
+		})
+	}
+
+	return actions
+}
+
+// lintErrorDiagnostic converts a lint error into an LSP diagnostic.
+func lintErrorDiagnostic(lintError models.LintError) diagnostic {
+	message := fmt.Sprintf("[%s] %s", lintError.Ruleset, lintError.Rule.Short)
+	if lintError.RuleErr.Suggestion != "" {
+		message = fmt.Sprintf("%s\n%s", message, lintError.RuleErr.Suggestion)
+	}
+
+	return diagnostic{
+		Range:    toLSPRange(lintError.RuleErr.Location),
+		Severity: toLSPSeverity(lintError.RuleErr.Metadata["severity"]),
+		Code:     lintError.Rule.ID,
+		Source:   diagnosticSource,
+		Message:  message,
+	}
+}
+
+// ruleDiagnosticDiagnostic converts a problem a rule ran into into an LSP diagnostic. Problems
+// without a location are shown at the top of the document.
+func ruleDiagnosticDiagnostic(ruleDiagnostic linter.RuleDiagnostic) diagnostic {
+	converted := diagnostic{
+		Severity: toLSPSeverity(string(ruleDiagnostic.Diagnostic.Severity)),
+		Code:     ruleDiagnostic.Rule.ID,
+		Source:   diagnosticSource,
+		Message: fmt.Sprintf("[%s] rule %q ran into a problem: %s",
+			ruleDiagnostic.Ruleset, ruleDiagnostic.Rule.Name, ruleDiagnostic.Diagnostic.Message),
+	}
+
+	if ruleDiagnostic.Diagnostic.Location != nil {
+		converted.Range = toLSPRange(*ruleDiagnostic.Diagnostic.Location)
+	}
+
+	return converted
+}
+
+// parseErrorDiagnostics converts the error returned for a file that could not be parsed into
+// LSP diagnostics.
+func parseErrorDiagnostics(err error) []diagnostic {
+	var hclDiags hcl.Diagnostics
+	if !errors.As(err, &hclDiags) {
+		return []diagnostic{{
+			Severity: severityError,
+			Source:   diagnosticSource,
+			Message:  err.Error(),
+		}}
+	}
+
+	diagnostics := []diagnostic{}
+	for _, hclDiag := range hclDiags {
+		if hclDiag.Severity != hcl.DiagError {
+			continue
+		}
+
+		diag := diagnostic{
+			Severity: severityError,
+			Source:   diagnosticSource,
+			Message:  hclDiag.Summary,
+		}
+
+		if hclDiag.Detail != "" {
+			diag.Message = fmt.Sprintf("%s: %s", hclDiag.Summary, hclDiag.Detail)
+		}
+
+		if hclDiag.Subject != nil {
+			diag.Range = toLSPRange(models.Range{
+				Start: models.Position{
+					Line:   uint32(hclDiag.Subject.Start.Line),
+					Column: uint32(hclDiag.Subject.Start.Column),
+				},
+				End: models.Position{
+					Line:   uint32(hclDiag.Subject.End.Line),
+					Column: uint32(hclDiag.Subject.End.Column),
+				},
+			})
+		}
+
+		diagnostics = append(diagnostics, diag)
+	}
+
+	return diagnostics
+}
+
+// formatRuleDocs returns the markdown documentation of the rule that produced a lint error.
+func formatRuleDocs(lintError models.LintError) string {
+	var docs strings.Builder
+
+	fmt.Fprintf(&docs, "**%s: %s** (%s)\n\n", lintError.Rule.ID, lintError.Rule.Name, lintError.Ruleset)
+	docs.WriteString(lintError.Rule.Short)
+
+	if long := strings.TrimSpace(lintError.Rule.Long); long != "" {
+		fmt.Fprintf(&docs, "\n\n%s", long)
+	}
+
+	if lintError.Rule.Link != "" {
+		fmt.Fprintf(&docs, "\n\n[More information](%s)", lintError.Rule.Link)
+	}
+
+	return docs.String()
+}
+
+// toLSPRange converts a tfvet range, which has 1-based lines and columns, into an LSP range,
+// which is 0-based.
+func toLSPRange(r models.Range) lspRange {
+	toPosition := func(pos models.Position) position {
+		converted := position{}
+		if pos.Line > 0 {
+			converted.Line = int(pos.Line) - 1
+		}
+		if pos.Column > 0 {
+			converted.Character = int(pos.Column) - 1
+		}
+		return converted
+	}
+
+	return lspRange{
+		Start: toPosition(r.Start),
+		End:   toPosition(r.End),
+	}
+}
+
+// toLSPSeverity maps the severity a rule reports through its metadata onto an LSP severity.
+// Lint errors without a recognized severity are shown as warnings.
+func toLSPSeverity(severity string) int {
+	switch strings.ToLower(severity) {
+	case "error":
+		return severityError
+	case "info", "information":
+		return severityInformation
+	case "hint":
+		return severityHint
+	default:
+		return severityWarning
+	}
+}
+
+// uriToPath returns the filesystem path of a file uri. Other uris are returned as-is.
+func uriToPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+
+	return parsed.Path
+}
@@ -0,0 +1,296 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	"github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+const testURI = "file:///tmp/main.tf"
+
+// exampleNameCheck flags resources named "example", like the rule generated by `tfvet rule create`.
+type exampleNameCheck struct{}
+
+func (c *exampleNameCheck) Check(content []byte) ([]sdk.RuleError, error) {
+	body := sdk.ParseHCL(content)
+	ruleErrors := []sdk.RuleError{}
+
+	for _, block := range body.Blocks {
+		if len(block.Labels) < 2 || block.Labels[1] != "example" {
+			continue
+		}
+
+		r := block.DefRange()
+		ruleErrors = append(ruleErrors, sdk.RuleError{
+			Suggestion:  "Use a different resource name than example",
+			Remediation: `resource "` + block.Labels[0] + `" "<new_name>" {`,
+			Location: sdk.Range{
+				Start: sdk.Position{Line: uint32(r.Start.Line), Column: uint32(r.Start.Column)},
+				End:   sdk.Position{Line: uint32(r.End.Line), Column: uint32(r.End.Column)},
+			},
+			Metadata: map[string]string{"severity": "error"},
+		})
+	}
+
+	return ruleErrors, nil
+}
+
+// testClient is an in-process JSON-RPC client connected to a server.
+type testClient struct {
+	t             *testing.T
+	conn          *conn
+	nextID        int
+	notifications []*message
+	done          chan error
+}
+
+func newTestClient(t *testing.T) *testClient {
+	rule := &sdk.Rule{
+		ID:      "EX001",
+		Name:    "No resource with name example",
+		Short:   "Example is a poor name for a resource.",
+		Long:    "Resources named example might lead to naming collisions.",
+		Link:    "https://example.com/EX001",
+		Enabled: true,
+		Check:   &exampleNameCheck{},
+	}
+
+	l := linter.New([]sdk.Ruleset{{
+		Name:    "test",
+		Enabled: true,
+		Rules:   []sdk.Rule{*rule},
+	}})
+	l.RegisterRule("test", rule.ID, rule)
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	client := &testClient{
+		t:    t,
+		conn: newConn(clientIn, clientOut),
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		err := NewServer(l, "test").Run(serverIn, serverOut)
+		serverOut.Close()
+		client.done <- err
+	}()
+
+	return client
+}
+
+// call sends a request and waits for its response, recording any notifications received meanwhile.
+func (c *testClient) call(method string, params, result interface{}) {
+	c.t.Helper()
+
+	c.nextID++
+	id := mustMarshal(c.t, c.nextID)
+
+	err := c.conn.write(&message{ID: &id, Method: method, Params: mustMarshal(c.t, params)})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	for {
+		msg, err := c.conn.read()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+
+		if msg.Error != nil {
+			c.t.Fatalf("%s failed: %v", method, msg.Error)
+		}
+
+		if result != nil {
+			err = json.Unmarshal(msg.Result, result)
+			if err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return
+	}
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	c.t.Helper()
+
+	err := c.conn.notify(method, params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// diagnostics waits for the next diagnostics published by the server.
+func (c *testClient) diagnostics() publishDiagnosticsParams {
+	c.t.Helper()
+
+	for {
+		var msg *message
+		if len(c.notifications) > 0 {
+			msg, c.notifications = c.notifications[0], c.notifications[1:]
+		} else {
+			var err error
+			msg, err = c.conn.read()
+			if err != nil {
+				c.t.Fatal(err)
+			}
+		}
+
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+
+		params := publishDiagnosticsParams{}
+		err := json.Unmarshal(msg.Params, &params)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		return params
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) json.RawMessage {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestServer(t *testing.T) {
+	client := newTestClient(t)
+
+	var initResult initializeResult
+	client.call("initialize", map[string]interface{}{}, &initResult)
+	if !initResult.Capabilities.HoverProvider || !initResult.Capabilities.CodeActionProvider {
+		t.Fatalf("expected hover and code action support; got %+v", initResult.Capabilities)
+	}
+	client.notify("initialized", map[string]interface{}{})
+
+	client.notify("textDocument/didOpen", didOpenTextDocumentParams{
+		TextDocument: textDocumentItem{
+			URI:        testURI,
+			LanguageID: "terraform",
+			Version:    1,
+			Text:       "resource \"google_compute_instance\" \"example\" {\n  name = \"test\"\n}\n",
+		},
+	})
+
+	diags := client.diagnostics()
+	if len(diags.Diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic; got %+v", diags.Diagnostics)
+	}
+
+	diag := diags.Diagnostics[0]
+	if diag.Code != "EX001" || diag.Severity != severityError || diag.Range.Start != (position{0, 0}) {
+		t.Fatalf("unexpected diagnostic %+v", diag)
+	}
+
+	var hoverResult hover
+	client.call("textDocument/hover", textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: testURI},
+		Position:     position{Line: 0, Character: 10},
+	}, &hoverResult)
+	if !strings.Contains(hoverResult.Contents.Value, "naming collisions") ||
+		!strings.Contains(hoverResult.Contents.Value, "https://example.com/EX001") {
+		t.Fatalf("hover does not contain rule docs: %q", hoverResult.Contents.Value)
+	}
+
+	var actions []codeAction
+	client.call("textDocument/codeAction", codeActionParams{
+		TextDocument: textDocumentIdentifier{URI: testURI},
+		Range:        diag.Range,
+	}, &actions)
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 code action; got %+v", actions)
+	}
+
+	edits := actions[0].Edit.Changes[testURI]
+	if len(edits) != 1 || edits[0].NewText != "resource \"google_compute_instance\" \"<new_name>\" {\n" ||
+		edits[0].Range != (lspRange{Start: position{0, 0}, End: position{1, 0}}) {
+		t.Fatalf("unexpected edit %+v", edits)
+	}
+
+	// Fixing the document should clear the diagnostics.
+	client.notify("textDocument/didChange", didChangeTextDocumentParams{
+		TextDocument: versionedTextDocumentIdentifier{URI: testURI, Version: 2},
+		ContentChanges: []textDocumentContentChangeEvent{{
+			Text: "resource \"google_compute_instance\" \"web\" {\n  name = \"test\"\n}\n",
+		}},
+	})
+
+	diags = client.diagnostics()
+	if len(diags.Diagnostics) != 0 || diags.Version != 2 {
+		t.Fatalf("expected no diagnostics for version 2; got %+v", diags)
+	}
+
+	// Syntax errors are reported as diagnostics too.
+	client.notify("textDocument/didChange", didChangeTextDocumentParams{
+		TextDocument:   versionedTextDocumentIdentifier{URI: testURI, Version: 3},
+		ContentChanges: []textDocumentContentChangeEvent{{Text: "resource \"a\" {\n"}},
+	})
+
+	diags = client.diagnostics()
+	if len(diags.Diagnostics) == 0 || diags.Diagnostics[0].Severity != severityError {
+		t.Fatalf("expected a syntax error diagnostic; got %+v", diags)
+	}
+
+	client.call("shutdown", nil, nil)
+	client.notify("exit", nil)
+
+	err := <-client.done
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	body := `{"jsonrpc": "2.0", "id": 1, "method": `
+	input := strings.NewReader(fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body))
+	output := &bytes.Buffer{}
+
+	err := NewServer(linter.New(nil), "test").Run(input, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ID of a request that failed to parse is unknown, but must be included as null.
+	response := strings.SplitN(output.String(), "\r\n\r\n", 2)[1]
+	if response != `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected end of JSON input"}}` {
+		t.Fatalf("unexpected response %s", response)
+	}
+}
+
+func TestFileLevelLintErrors(t *testing.T) {
+	server := NewServer(linter.New(nil), "test")
+	server.documents[testURI] = &document{lintErrors: []sdk.LintError{{
+		Rule:    sdk.Rule{ID: "EX002", Name: "Pinned versions"},
+		RuleErr: sdk.RuleError{Suggestion: "pin provider versions", Remediation: "terraform {}"},
+	}}}
+
+	// Lint errors without a location aren't at the start of the document.
+	if result := server.hover(textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: testURI},
+	}); result != nil {
+		t.Fatalf("expected no hover for a lint error without a location; got %+v", result)
+	}
+
+	if actions := server.codeActions(codeActionParams{
+		TextDocument: textDocumentIdentifier{URI: testURI},
+		Range:        lspRange{End: position{Line: 1}},
+	}); len(actions) != 0 {
+		t.Fatalf("expected no quick fix for a lint error without a location; got %+v", actions)
+	}
+}
@@ -2,8 +2,13 @@ package plugin
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
 
 	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
 	"google.golang.org/grpc"
 )
@@ -22,8 +27,17 @@ func (p *TfvetRulePlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBro
 // of the rpc method for that specific plugin and return the result
 
 // ExecuteRule calls the corresponding ExecuteRule on the plugin through the GRPC client
-func (m *GRPCClient) ExecuteRule(request *proto.ExecuteRuleRequest) (*proto.ExecuteRuleResponse, error) {
-	response, err := m.client.ExecuteRule(context.Background(), request)
+func (m *GRPCClient) ExecuteRule(ctx context.Context, request *proto.ExecuteRuleRequest) (*proto.ExecuteRuleResponse, error) {
+	response, err := m.client.ExecuteRule(ctx, request)
+	if err != nil {
+		return &proto.ExecuteRuleResponse{}, err
+	}
+	return response, nil
+}
+
+// ExecutePlanRule calls the corresponding ExecutePlanRule on the plugin through the GRPC client
+func (m *GRPCClient) ExecutePlanRule(ctx context.Context, request *proto.ExecutePlanRuleRequest) (*proto.ExecuteRuleResponse, error) {
+	response, err := m.client.ExecutePlanRule(ctx, request)
 	if err != nil {
 		return &proto.ExecuteRuleResponse{}, err
 	}
@@ -38,3 +52,65 @@ func (m *GRPCClient) GetRuleInfo(request *proto.GetRuleInfoRequest) (*proto.GetR
 	}
 	return response, nil
 }
+
+// ListRules calls the corresponding ListRules method on the plugin through the GRPC client
+func (m *GRPCClient) ListRules(request *proto.ListRulesRequest) (*proto.ListRulesResponse, error) {
+	response, err := m.client.ListRules(context.Background(), request)
+	if err != nil {
+		return &proto.ListRulesResponse{}, err
+	}
+	return response, nil
+}
+
+// pluginName is the name rules are dispensed under. Both the rules and the host need to agree on it.
+const pluginName = "tfvetPlugin"
+
+// Connect starts the rule plugin at the given path and returns the go-plugin client along with
+// the rule definition used to talk to it.
+//
+// Logs written by the plugin's rules are passed on to logger, usually the one returned by
+// RulesetLogger, named after the rule that wrote them. A nil logger discards them.
+//
+// YOU MUST call Kill() on the returned plugin.Client object or it will leak the plugin process.
+func Connect(path string, logger hclog.Logger) (*plugin.Client, RuleDefinition, error) {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig: Handshake,
+		Plugins: map[string]plugin.Plugin{
+			pluginName: &TfvetRulePlugin{},
+		},
+		Cmd: exec.Command(path),
+		// go-plugin logs everything the plugin writes to stderr under the name of its binary, which
+		// says little about the rule that wrote it. We log it ourselves instead.
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Output: ioutil.Discard,
+			Level:  0,
+			Name:   "plugin",
+		}),
+		Stderr:           newLogWriter(logger),
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+	})
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("could not create rpc client: %w", err)
+	}
+
+	raw, err := rpcClient.Dispense(pluginName)
+	if err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("could not connect to rule plugin: %w", err)
+	}
+
+	rule, ok := raw.(RuleDefinition)
+	if !ok {
+		client.Kill()
+		return nil, nil, errors.New("could not convert rule interface")
+	}
+
+	return client, rule, nil
+}
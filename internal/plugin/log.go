@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// NewLogger returns the logger rules log to, writing to stderr. Level is one of trace, debug,
+// info, warn, error or off.
+func NewLogger(level string) (hclog.Logger, error) {
+	parsed := hclog.LevelFromString(level)
+	if parsed == hclog.NoLevel {
+		return nil, fmt.Errorf("unknown log level %q; accepted values are 'trace', 'debug', 'info',"+
+			" 'warn', 'error', 'off'", level)
+	}
+
+	return hclog.New(&hclog.LoggerOptions{
+		Level:  parsed,
+		Output: os.Stderr,
+	}), nil
+}
+
+// RulesetLogger returns the logger for the rules of a ruleset, named after the ruleset.
+func RulesetLogger(logger hclog.Logger, ruleset string) hclog.Logger {
+	return logger.ResetNamed(ruleset)
+}
+
+// RuleLogger returns the logger for a single rule, named after the rule's ruleset and ID. Logs of
+// rules run as plugins end up with the same name.
+func RuleLogger(logger hclog.Logger, ruleset, ruleID string) hclog.Logger {
+	return RulesetLogger(logger, ruleset).Named(ruleID)
+}
+
+// logWriter receives the output plugins write to stderr and passes it on to a logger. Rules log in
+// hclog's JSON format, named after the rule that wrote the log. Anything else is logged as is at
+// the debug level.
+type logWriter struct {
+	logger hclog.Logger
+
+	mu   sync.Mutex
+	line []byte
+}
+
+func newLogWriter(logger hclog.Logger) *logWriter {
+	return &logWriter{logger: logger}
+}
+
+// Write buffers output until it forms complete lines and logs each of them.
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.line = append(w.line, p...)
+
+	for {
+		index := bytes.IndexByte(w.line, '\n')
+		if index < 0 {
+			break
+		}
+
+		w.log(w.line[:index])
+		w.line = w.line[index+1:]
+	}
+
+	return len(p), nil
+}
+
+// log logs a single line of plugin output.
+func (w *logWriter) log(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	entry := map[string]interface{}{}
+	err := json.Unmarshal(line, &entry)
+	if err != nil {
+		w.logger.Debug(string(line))
+		return
+	}
+
+	message, _ := entry["@message"].(string)
+	level, _ := entry["@level"].(string)
+	name, _ := entry["@module"].(string)
+
+	logger := w.logger
+	if name != "" {
+		logger = logger.Named(name)
+	}
+
+	args := []interface{}{}
+	for key, value := range entry {
+		if len(key) > 0 && key[0] == '@' {
+			continue
+		}
+		args = append(args, key, value)
+	}
+
+	logger.Log(hclog.LevelFromString(level), message, args...)
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestLogWriter(t *testing.T) {
+	var output bytes.Buffer
+	logger := hclog.New(&hclog.LoggerOptions{
+		Level:      hclog.Trace,
+		Output:     &output,
+		JSONFormat: true,
+	})
+
+	writer := newLogWriter(RulesetLogger(logger, "example"))
+
+	// Lines are only logged once complete, however the output is split up.
+	chunks := []string{
+		`{"@level":"warn","@message":"skipped block",`,
+		`"@module":"EX001","address":"aws_s3_bucket.logs"}` + "\n" + "panic: ",
+		"something broke\n\n",
+	}
+	for index, chunk := range chunks {
+		_, err := writer.Write([]byte(chunk))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if index == 0 && output.Len() != 0 {
+			t.Fatalf("expected partial lines not to be logged; got %q", output.String())
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines; got %q", lines)
+	}
+
+	entries := []map[string]interface{}{}
+	for _, line := range lines {
+		entry := map[string]interface{}{}
+		err := json.Unmarshal([]byte(line), &entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if entries[0]["@module"] != "example.EX001" || entries[0]["@level"] != "warn" ||
+		entries[0]["@message"] != "skipped block" || entries[0]["address"] != "aws_s3_bucket.logs" {
+		t.Errorf("expected the rule's log to be logged under its ruleset at its own level; got %v", entries[0])
+	}
+
+	if entries[1]["@module"] != "example" || entries[1]["@level"] != "debug" ||
+		entries[1]["@message"] != "panic: something broke" {
+		t.Errorf("expected other output to be logged as is at the debug level; got %v", entries[1])
+	}
+}
@@ -8,6 +8,8 @@
 package plugin
 
 import (
+	"context"
+
 	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
 	"github.com/hashicorp/go-plugin"
 )
@@ -30,9 +32,14 @@ var Handshake = plugin.HandshakeConfig{
 }
 
 // RuleDefinition is the interface in which both the plugin and the host has to implement
+//
+// Executing a rule takes a context. Rules should stop once it is cancelled, for example because its
+// deadline passed.
 type RuleDefinition interface {
-	ExecuteRule(request *proto.ExecuteRuleRequest) (*proto.ExecuteRuleResponse, error)
+	ExecuteRule(ctx context.Context, request *proto.ExecuteRuleRequest) (*proto.ExecuteRuleResponse, error)
+	ExecutePlanRule(ctx context.Context, request *proto.ExecutePlanRuleRequest) (*proto.ExecuteRuleResponse, error)
 	GetRuleInfo(request *proto.GetRuleInfoRequest) (*proto.GetRuleInfoResponse, error)
+	ListRules(request *proto.ListRulesRequest) (*proto.ListRulesResponse, error)
 }
 
 // TfvetRulePlugin is just a wrapper so we implement the correct go-plugin interface
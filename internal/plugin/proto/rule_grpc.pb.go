@@ -19,6 +19,8 @@ const _ = grpc.SupportPackageIsVersion7
 type TfvetRulePluginClient interface {
 	GetRuleInfo(ctx context.Context, in *GetRuleInfoRequest, opts ...grpc.CallOption) (*GetRuleInfoResponse, error)
 	ExecuteRule(ctx context.Context, in *ExecuteRuleRequest, opts ...grpc.CallOption) (*ExecuteRuleResponse, error)
+	ExecutePlanRule(ctx context.Context, in *ExecutePlanRuleRequest, opts ...grpc.CallOption) (*ExecuteRuleResponse, error)
+	ListRules(ctx context.Context, in *ListRulesRequest, opts ...grpc.CallOption) (*ListRulesResponse, error)
 }
 
 type tfvetRulePluginClient struct {
@@ -47,12 +49,32 @@ func (c *tfvetRulePluginClient) ExecuteRule(ctx context.Context, in *ExecuteRule
 	return out, nil
 }
 
+func (c *tfvetRulePluginClient) ExecutePlanRule(ctx context.Context, in *ExecutePlanRuleRequest, opts ...grpc.CallOption) (*ExecuteRuleResponse, error) {
+	out := new(ExecuteRuleResponse)
+	err := c.cc.Invoke(ctx, "/proto.TfvetRulePlugin/ExecutePlanRule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tfvetRulePluginClient) ListRules(ctx context.Context, in *ListRulesRequest, opts ...grpc.CallOption) (*ListRulesResponse, error) {
+	out := new(ListRulesResponse)
+	err := c.cc.Invoke(ctx, "/proto.TfvetRulePlugin/ListRules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TfvetRulePluginServer is the server API for TfvetRulePlugin service.
 // All implementations must embed UnimplementedTfvetRulePluginServer
 // for forward compatibility
 type TfvetRulePluginServer interface {
 	GetRuleInfo(context.Context, *GetRuleInfoRequest) (*GetRuleInfoResponse, error)
 	ExecuteRule(context.Context, *ExecuteRuleRequest) (*ExecuteRuleResponse, error)
+	ExecutePlanRule(context.Context, *ExecutePlanRuleRequest) (*ExecuteRuleResponse, error)
+	ListRules(context.Context, *ListRulesRequest) (*ListRulesResponse, error)
 	mustEmbedUnimplementedTfvetRulePluginServer()
 }
 
@@ -66,6 +88,12 @@ func (UnimplementedTfvetRulePluginServer) GetRuleInfo(context.Context, *GetRuleI
 func (UnimplementedTfvetRulePluginServer) ExecuteRule(context.Context, *ExecuteRuleRequest) (*ExecuteRuleResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ExecuteRule not implemented")
 }
+func (UnimplementedTfvetRulePluginServer) ExecutePlanRule(context.Context, *ExecutePlanRuleRequest) (*ExecuteRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExecutePlanRule not implemented")
+}
+func (UnimplementedTfvetRulePluginServer) ListRules(context.Context, *ListRulesRequest) (*ListRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRules not implemented")
+}
 func (UnimplementedTfvetRulePluginServer) mustEmbedUnimplementedTfvetRulePluginServer() {}
 
 // UnsafeTfvetRulePluginServer may be embedded to opt out of forward compatibility for this service.
@@ -115,6 +143,42 @@ func _TfvetRulePlugin_ExecuteRule_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TfvetRulePlugin_ExecutePlanRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecutePlanRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TfvetRulePluginServer).ExecutePlanRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.TfvetRulePlugin/ExecutePlanRule",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TfvetRulePluginServer).ExecutePlanRule(ctx, req.(*ExecutePlanRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TfvetRulePlugin_ListRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TfvetRulePluginServer).ListRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.TfvetRulePlugin/ListRules",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TfvetRulePluginServer).ListRules(ctx, req.(*ListRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _TfvetRulePlugin_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.TfvetRulePlugin",
 	HandlerType: (*TfvetRulePluginServer)(nil),
@@ -127,6 +191,14 @@ var _TfvetRulePlugin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ExecuteRule",
 			Handler:    _TfvetRulePlugin_ExecuteRule_Handler,
 		},
+		{
+			MethodName: "ExecutePlanRule",
+			Handler:    _TfvetRulePlugin_ExecutePlanRule_Handler,
+		},
+		{
+			MethodName: "ListRules",
+			Handler:    _TfvetRulePlugin_ListRules_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/plugin/proto/rule.proto",
@@ -26,7 +26,13 @@ func (p *TfvetRulePlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server)
 
 // ExecuteRule executes a single rule on a plugin
 func (m *GRPCServer) ExecuteRule(ctx context.Context, request *proto.ExecuteRuleRequest) (*proto.ExecuteRuleResponse, error) {
-	response, err := m.Impl.ExecuteRule(request)
+	response, err := m.Impl.ExecuteRule(ctx, request)
+	return response, err
+}
+
+// ExecutePlanRule executes a single rule against a terraform plan on a plugin
+func (m *GRPCServer) ExecutePlanRule(ctx context.Context, request *proto.ExecutePlanRuleRequest) (*proto.ExecuteRuleResponse, error) {
+	response, err := m.Impl.ExecutePlanRule(ctx, request)
 	return response, err
 }
 
@@ -35,3 +41,9 @@ func (m *GRPCServer) GetRuleInfo(ctx context.Context, request *proto.GetRuleInfo
 	response, err := m.Impl.GetRuleInfo(request)
 	return response, err
 }
+
+// ListRules gets information about all rules served by the plugin
+func (m *GRPCServer) ListRules(ctx context.Context, request *proto.ListRulesRequest) (*proto.ListRulesResponse, error) {
+	response, err := m.Impl.ListRules(request)
+	return response, err
+}
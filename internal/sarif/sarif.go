@@ -0,0 +1,276 @@
+// Package sarif writes lint results in the Static Analysis Results Interchange Format (SARIF), which
+// code scanning tools like GitHub's read to show lint errors alongside the code.
+//
+// Lint errors are reported as results. Problems rules ran into, their diagnostics and failures, are
+// reported as notifications of the run so that they stay apart from the lint errors themselves.
+//
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html for the format.
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+const (
+	version = "2.1.0"
+	schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	toolName = "tfvet"
+	toolURI  = "https://github.com/clintjedwards/tfvet"
+)
+
+// Log is the struct representation of a SARIF file holding a single run of tfvet.
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is a single run of tfvet.
+type Run struct {
+	Tool        Tool         `json:"tool"`
+	Invocations []Invocation `json:"invocations"`
+	Results     []Result     `json:"results"`
+
+	rules map[string]bool // IDs of the rules in the tool's driver.
+}
+
+// Tool describes tfvet and the rules it ran.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is tfvet itself.
+type Driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri"`
+	Rules          []Rule `json:"rules"`
+}
+
+// Rule describes a rule that reported lint errors or problems.
+type Rule struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name,omitempty"`
+	ShortDescription *Message        `json:"shortDescription,omitempty"`
+	FullDescription  *Message        `json:"fullDescription,omitempty"`
+	HelpURI          string          `json:"helpUri,omitempty"`
+	Properties       *RuleProperties `json:"properties,omitempty"`
+}
+
+// RuleProperties are the tags of a rule, which include its category.
+type RuleProperties struct {
+	Tags []string `json:"tags"`
+}
+
+// Invocation is the execution of tfvet, along with the problems rules ran into during it.
+type Invocation struct {
+	ExecutionSuccessful bool           `json:"executionSuccessful"`
+	Notifications       []Notification `json:"toolExecutionNotifications"`
+}
+
+// Notification is a problem a rule ran into.
+type Notification struct {
+	Level          string               `json:"level"`
+	Message        Message              `json:"message"`
+	Locations      []Location           `json:"locations,omitempty"`
+	AssociatedRule *ReportingDescriptor `json:"associatedRule,omitempty"`
+}
+
+// ReportingDescriptor refers to a rule of the tool's driver.
+type ReportingDescriptor struct {
+	ID string `json:"id"`
+}
+
+// Result is a single lint error.
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations"`
+}
+
+// Message is the text of a result, notification or description.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Location is a region of a file.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation is a region of a file.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is the file a location is in.
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region is where in a file a location is. Lines and columns start at 1.
+type Region struct {
+	StartLine   uint32 `json:"startLine"`
+	StartColumn uint32 `json:"startColumn,omitempty"`
+	EndLine     uint32 `json:"endLine,omitempty"`
+	EndColumn   uint32 `json:"endColumn,omitempty"`
+}
+
+// New returns an empty log for the given version of tfvet.
+func New(toolVersion string) *Log {
+	return &Log{
+		Version: version,
+		Schema:  schema,
+		Runs: []Run{{
+			Tool: Tool{Driver: Driver{
+				Name:           toolName,
+				Version:        toolVersion,
+				InformationURI: toolURI,
+				Rules:          []Rule{},
+			}},
+			Invocations: []Invocation{{ExecutionSuccessful: true, Notifications: []Notification{}}},
+			Results:     []Result{},
+			rules:       map[string]bool{},
+		}},
+	}
+}
+
+// Add records the lint errors of the given result, along with the problems rules ran into.
+func (l *Log) Add(result *linter.Result) {
+	run := &l.Runs[0]
+	invocation := &run.Invocations[0]
+
+	for _, failure := range result.Failures {
+		invocation.ExecutionSuccessful = false
+		invocation.Notifications = append(invocation.Notifications, Notification{
+			Level:          "error",
+			Message:        Message{Text: fmt.Sprintf("rule failed to run: %v", failure.Err)},
+			Locations:      []Location{location(result.Filepath, nil)},
+			AssociatedRule: &ReportingDescriptor{ID: run.addRule(failure.Ruleset, failure.Rule)},
+		})
+	}
+
+	for _, diagnostic := range result.Diagnostics {
+		if diagnostic.Diagnostic.Severity == models.DiagnosticError {
+			invocation.ExecutionSuccessful = false
+		}
+
+		invocation.Notifications = append(invocation.Notifications, Notification{
+			Level:          notificationLevel(diagnostic.Diagnostic.Severity),
+			Message:        Message{Text: diagnostic.Diagnostic.Message},
+			Locations:      []Location{location(diagnostic.Filepath, diagnostic.Diagnostic.Location)},
+			AssociatedRule: &ReportingDescriptor{ID: run.addRule(diagnostic.Ruleset, diagnostic.Rule)},
+		})
+	}
+
+	for _, lintError := range result.LintErrors {
+		message := lintError.RuleErr.Suggestion
+		if lintError.RuleErr.Remediation != "" {
+			message = fmt.Sprintf("%s\n\n%s", message, lintError.RuleErr.Remediation)
+		}
+
+		errorLocation := lintError.RuleErr.Location
+		run.Results = append(run.Results, Result{
+			RuleID:    run.addRule(lintError.Ruleset, lintError.Rule),
+			Level:     "error",
+			Message:   Message{Text: message},
+			Locations: []Location{location(lintError.Filepath, &errorLocation)},
+		})
+	}
+}
+
+// Write writes the log to the given path.
+func (l *Log) Write(path string) error {
+	contents, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(contents, '\n'), 0644)
+}
+
+// addRule adds a rule to the tool's driver, unless it was added before, and returns the ID it's
+// referred to by. Rules of different rulesets may share an ID, so the ID includes the ruleset.
+func (r *Run) addRule(ruleset string, rule models.Rule) string {
+	id := ruleset + "/" + rule.ID
+	if r.rules[id] {
+		return id
+	}
+	r.rules[id] = true
+
+	sarifRule := Rule{
+		ID:      id,
+		Name:    rule.Name,
+		HelpURI: rule.Link,
+	}
+	if rule.Short != "" {
+		sarifRule.ShortDescription = &Message{Text: rule.Short}
+	}
+	if long := strings.TrimSpace(rule.Long); long != "" {
+		sarifRule.FullDescription = &Message{Text: long}
+	}
+
+	tags := append([]string{}, rule.Tags...)
+	if rule.Category != "" {
+		tags = append(tags, string(rule.Category))
+	}
+	if len(tags) > 0 {
+		sarifRule.Properties = &RuleProperties{Tags: tags}
+	}
+
+	r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, sarifRule)
+
+	return id
+}
+
+// notificationLevel returns the SARIF level of a diagnostic's severity.
+func notificationLevel(severity models.DiagnosticSeverity) string {
+	if severity == models.DiagnosticError {
+		return "error"
+	}
+
+	return "warning"
+}
+
+// location returns the location of a range within a file. Files are referred to relative to the
+// working directory where possible, since that's usually the root of the repository being linted.
+func location(path string, fileRange *models.Range) Location {
+	physical := PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: relativeURI(path)}}
+
+	if fileRange != nil && fileRange.Start.Line > 0 {
+		physical.Region = &Region{
+			StartLine:   fileRange.Start.Line,
+			StartColumn: fileRange.Start.Column,
+			EndLine:     fileRange.End.Line,
+			EndColumn:   fileRange.End.Column,
+		}
+	}
+
+	return Location{PhysicalLocation: physical}
+}
+
+// relativeURI returns the path relative to the working directory, if it's within it, in the form
+// of a URI reference.
+func relativeURI(path string) string {
+	if filepath.IsAbs(path) {
+		cwd, err := os.Getwd()
+		if err == nil {
+			if relPath, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(relPath, "..") {
+				path = relPath
+			}
+		}
+	}
+
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,98 @@
+package sarif
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/internal/linter"
+	models "github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+func TestLog(t *testing.T) {
+	rule := models.Rule{ID: "EX001", Name: "No example names", Short: "Example is a poor name.",
+		Category: models.CategoryStyle, Tags: []string{"naming"}}
+	other := models.Rule{ID: "EX001", Name: "Other"}
+
+	sarifLog := New("2.0.0")
+	sarifLog.Add(&linter.Result{
+		Filepath: "main.tf",
+		LintErrors: []models.LintError{
+			{Filepath: "main.tf", Ruleset: "example", Rule: rule, RuleErr: models.RuleError{
+				Suggestion:  "Rename the resource",
+				Remediation: `resource "a" "logs" {}`,
+				Location:    models.Range{Start: models.Position{Line: 2, Column: 1}, End: models.Position{Line: 2, Column: 20}},
+			}},
+			{Filepath: "main.tf", Ruleset: "example", Rule: rule, RuleErr: models.RuleError{
+				Suggestion: "Rename the resource",
+				Location:   models.Range{Start: models.Position{Line: 5, Column: 1}},
+			}},
+		},
+		Diagnostics: []linter.RuleDiagnostic{{
+			Filepath: "main.tf", Ruleset: "other", Rule: other,
+			Diagnostic: models.Diagnostic{Severity: models.DiagnosticWarning, Message: "skipped dynamic block"},
+		}},
+	})
+	sarifLog.Add(&linter.Result{
+		Filepath: "vars.tf",
+		Failures: []linter.RuleFailure{{Ruleset: "example", Rule: rule, Err: errors.New("plugin crashed")}},
+	})
+
+	path := filepath.Join(t.TempDir(), "tfvet.sarif")
+	err := sarifLog.Write(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written Log
+	err = json.Unmarshal(contents, &written)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if written.Version != "2.1.0" || len(written.Runs) != 1 {
+		t.Fatalf("expected a single SARIF 2.1.0 run; got %+v", written)
+	}
+	run := written.Runs[0]
+
+	// Rules are listed once, and rules of different rulesets sharing an ID are kept apart.
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "other/EX001" ||
+		run.Tool.Driver.Rules[1].ID != "example/EX001" {
+		t.Fatalf("unexpected rules %+v", run.Tool.Driver.Rules)
+	}
+	if tags := run.Tool.Driver.Rules[1].Properties.Tags; len(tags) != 2 || tags[1] != "style" {
+		t.Fatalf("expected the rule's tags and category; got %v", tags)
+	}
+
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 results; got %+v", run.Results)
+	}
+	result := run.Results[0]
+	region := result.Locations[0].PhysicalLocation.Region
+	if result.RuleID != "example/EX001" || result.Message.Text != "Rename the resource\n\nresource \"a\" \"logs\" {}" ||
+		result.Locations[0].PhysicalLocation.ArtifactLocation.URI != "main.tf" ||
+		region.StartLine != 2 || region.EndColumn != 20 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	invocation := run.Invocations[0]
+	if invocation.ExecutionSuccessful || len(invocation.Notifications) != 2 {
+		t.Fatalf("expected the failure and the diagnostic to be reported; got %+v", invocation)
+	}
+	if notification := invocation.Notifications[0]; notification.Level != "warning" ||
+		notification.AssociatedRule.ID != "other/EX001" {
+		t.Fatalf("unexpected notification for the diagnostic %+v", notification)
+	}
+	if notification := invocation.Notifications[1]; notification.Level != "error" ||
+		notification.Locations[0].PhysicalLocation.ArtifactLocation.URI != "vars.tf" ||
+		notification.Locations[0].PhysicalLocation.Region != nil {
+		t.Fatalf("unexpected notification for the failure %+v", notification)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"context"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"time"
 )
 
@@ -27,6 +29,42 @@ func CreateDir(dirPath string) error {
 	return nil
 }
 
+// WriteFileAtomic writes data to the file at path, creating or replacing it. The data is first
+// written to a temporary file which then replaces the file, so that an interrupted write can never
+// leave behind a partially written file and concurrent readers see either the old or new contents.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	// The temporary file needs to be in the same directory so the rename below is atomic.
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name()) // Clean up in case we fail before the rename.
+
+	_, err = tmpFile.Write(data)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Sync()
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmpFile.Name(), perm)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
 // ExecuteCmd wraps context around a given command and executes it.
 func ExecuteCmd(path string, args []string, env []string, dir string) ([]byte, error) {
 	// Create context with timeout
@@ -0,0 +1,316 @@
+package sdk
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// moduleSchema is the part of a terraform file's schema that is needed to evaluate expressions.
+var moduleSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "locals"},
+	},
+}
+
+// variableSchema is the part of a variable block's schema that is needed to evaluate expressions.
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "default"}},
+}
+
+// Module is the terraform module a linted file belongs to. It evaluates expressions of the
+// module's files using the values of its input variables and local values.
+//
+// Input variables take their values from the variable definitions files terraform loads
+// automatically (terraform.tfvars and *.auto.tfvars) or, if not set there, from their defaults.
+// Anything that is only known once terraform runs, like input variables without a value, resource
+// attributes or calls to functions tfvet doesn't know about, is evaluated to an unknown value.
+type Module struct {
+	ctx   *hcl.EvalContext
+	files map[string][]byte
+}
+
+// NewModule returns the module made up of the given files, keyed by file name.
+func NewModule(files map[string][]byte) *Module {
+	module := &Module{
+		ctx: &hcl.EvalContext{
+			Variables: map[string]cty.Value{},
+			Functions: functions(),
+		},
+		files: files,
+	}
+
+	parser := hclparse.NewParser()
+	variables := map[string]cty.Value{}
+	locals := map[string]hcl.Expression{}
+
+	for _, name := range configurationFiles(files) {
+		file, _ := parse(parser, name, files[name])
+		if file == nil {
+			continue
+		}
+
+		content, _, _ := file.Body.PartialContent(moduleSchema)
+		if content == nil {
+			continue
+		}
+
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "variable":
+				variables[block.Labels[0]] = variableDefault(block)
+			case "locals":
+				attributes, _ := block.Body.JustAttributes()
+				for name, attribute := range attributes {
+					locals[name] = attribute.Expr
+				}
+			}
+		}
+	}
+
+	// Later variable definitions files override the values of earlier ones, just like in
+	// terraform. Values for undeclared variables are ignored.
+	for _, name := range variableDefinitionsFiles(files) {
+		file, _ := parse(parser, name, files[name])
+		if file == nil {
+			continue
+		}
+
+		attributes, _ := file.Body.JustAttributes()
+		for name, attribute := range attributes {
+			if _, ok := variables[name]; !ok {
+				continue
+			}
+
+			value, diags := attribute.Expr.Value(nil)
+			if diags.HasErrors() {
+				value = cty.DynamicVal
+			}
+			variables[name] = value
+		}
+	}
+
+	module.ctx.Variables["var"] = cty.ObjectVal(variables)
+	module.evaluateLocals(locals)
+
+	return module
+}
+
+// Files returns the contents of the module's files, keyed by file name. These are the module's
+// configuration files and the variable definitions files terraform loads automatically. Use it for
+// checks that need to look at the whole module, like finding declarations that are never used.
+func (m *Module) Files() map[string][]byte {
+	return m.files
+}
+
+// EvalContext returns the context expressions of the module are evaluated in. It contains the
+// module's input variables as "var", its local values as "local" and the functions tfvet knows
+// about. Other references, like resource attributes, aren't part of it; use Evaluate to have
+// them treated as unknown values.
+func (m *Module) EvalContext() *hcl.EvalContext {
+	return m.ctx
+}
+
+// Evaluate returns the value of the given expression. Values that can't be determined without
+// running terraform are returned as unknown values, so rules should check whether a value is
+// known (cty.Value.IsWhollyKnown) before comparing it to anything.
+func (m *Module) Evaluate(expr hcl.Expression) cty.Value {
+	ctx := m.ctx.NewChild()
+	ctx.Variables = map[string]cty.Value{}
+
+	for _, traversal := range expr.Variables() {
+		root := traversal.RootName()
+		if _, ok := m.ctx.Variables[root]; ok {
+			continue
+		}
+
+		ctx.Variables[root] = cty.DynamicVal
+	}
+
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return cty.DynamicVal
+	}
+
+	return value
+}
+
+// evaluateLocals evaluates the module's local values. Locals can refer to each other in any
+// order, so they are evaluated repeatedly until their values stop changing. Locals that can't be
+// resolved, for example because they refer to each other in a cycle, are left unknown.
+func (m *Module) evaluateLocals(locals map[string]hcl.Expression) {
+	values := map[string]cty.Value{}
+	for name := range locals {
+		values[name] = cty.DynamicVal
+	}
+	m.ctx.Variables["local"] = cty.ObjectVal(values)
+
+	for pass := 0; pass < len(locals); pass++ {
+		changed := false
+
+		updated := map[string]cty.Value{}
+		for name, expr := range locals {
+			updated[name] = m.Evaluate(expr)
+			if !updated[name].RawEquals(values[name]) {
+				changed = true
+			}
+		}
+
+		values = updated
+		m.ctx.Variables["local"] = cty.ObjectVal(values)
+
+		if !changed {
+			return
+		}
+	}
+}
+
+// variableDefault returns the default value of a variable block, or an unknown value if it
+// doesn't have one.
+func variableDefault(block *hcl.Block) cty.Value {
+	content, _, _ := block.Body.PartialContent(variableSchema)
+	if content == nil {
+		return cty.DynamicVal
+	}
+
+	attribute, ok := content.Attributes["default"]
+	if !ok {
+		return cty.DynamicVal
+	}
+
+	value, diags := attribute.Expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.DynamicVal
+	}
+
+	return value
+}
+
+// parse parses a module file using the syntax matching its name.
+func parse(parser *hclparse.Parser, name string, content []byte) (*hcl.File, hcl.Diagnostics) {
+	if strings.HasSuffix(name, ".json") {
+		return parser.ParseJSON(content, name)
+	}
+
+	return parser.ParseHCL(content, name)
+}
+
+// IsModuleFile returns whether a file with the given name is used when evaluating the expressions
+// of a module. These are the module's configuration files and the variable definitions files
+// terraform loads automatically.
+func IsModuleFile(name string) bool {
+	return isConfigurationFile(name) || isAutoVariableDefinitionsFile(name)
+}
+
+func isConfigurationFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")
+}
+
+func isAutoVariableDefinitionsFile(name string) bool {
+	return name == "terraform.tfvars" || name == "terraform.tfvars.json" ||
+		strings.HasSuffix(name, ".auto.tfvars") || strings.HasSuffix(name, ".auto.tfvars.json")
+}
+
+// configurationFiles returns the names of the configuration files among the given files, sorted.
+func configurationFiles(files map[string][]byte) []string {
+	names := []string{}
+	for name := range files {
+		if isConfigurationFile(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// variableDefinitionsFiles returns the names of the variable definitions files terraform loads
+// automatically among the given files, in the order terraform loads them: terraform.tfvars first,
+// followed by *.auto.tfvars files in lexical order.
+func variableDefinitionsFiles(files map[string][]byte) []string {
+	names := []string{}
+	for name := range files {
+		if isAutoVariableDefinitionsFile(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		iAuto, jAuto := strings.Contains(names[i], ".auto."), strings.Contains(names[j], ".auto.")
+		if iAuto != jAuto {
+			return jAuto
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// functions returns the terraform functions that can be evaluated by tfvet. These are the
+// functions that don't depend on the machine terraform runs on.
+func functions() map[string]function.Function {
+	return map[string]function.Function{
+		"abs":             stdlib.AbsoluteFunc,
+		"ceil":            stdlib.CeilFunc,
+		"chomp":           stdlib.ChompFunc,
+		"chunklist":       stdlib.ChunklistFunc,
+		"coalesce":        stdlib.CoalesceFunc,
+		"coalescelist":    stdlib.CoalesceListFunc,
+		"compact":         stdlib.CompactFunc,
+		"concat":          stdlib.ConcatFunc,
+		"contains":        stdlib.ContainsFunc,
+		"csvdecode":       stdlib.CSVDecodeFunc,
+		"distinct":        stdlib.DistinctFunc,
+		"element":         stdlib.ElementFunc,
+		"flatten":         stdlib.FlattenFunc,
+		"floor":           stdlib.FloorFunc,
+		"format":          stdlib.FormatFunc,
+		"formatdate":      stdlib.FormatDateFunc,
+		"formatlist":      stdlib.FormatListFunc,
+		"indent":          stdlib.IndentFunc,
+		"index":           stdlib.IndexFunc,
+		"join":            stdlib.JoinFunc,
+		"jsondecode":      stdlib.JSONDecodeFunc,
+		"jsonencode":      stdlib.JSONEncodeFunc,
+		"keys":            stdlib.KeysFunc,
+		"length":          stdlib.LengthFunc,
+		"log":             stdlib.LogFunc,
+		"lookup":          stdlib.LookupFunc,
+		"lower":           stdlib.LowerFunc,
+		"max":             stdlib.MaxFunc,
+		"merge":           stdlib.MergeFunc,
+		"min":             stdlib.MinFunc,
+		"parseint":        stdlib.ParseIntFunc,
+		"pow":             stdlib.PowFunc,
+		"range":           stdlib.RangeFunc,
+		"regex":           stdlib.RegexFunc,
+		"regexall":        stdlib.RegexAllFunc,
+		"replace":         stdlib.ReplaceFunc,
+		"reverse":         stdlib.ReverseListFunc,
+		"setintersection": stdlib.SetIntersectionFunc,
+		"setproduct":      stdlib.SetProductFunc,
+		"setsubtract":     stdlib.SetSubtractFunc,
+		"setunion":        stdlib.SetUnionFunc,
+		"signum":          stdlib.SignumFunc,
+		"slice":           stdlib.SliceFunc,
+		"sort":            stdlib.SortFunc,
+		"split":           stdlib.SplitFunc,
+		"strrev":          stdlib.ReverseFunc,
+		"substr":          stdlib.SubstrFunc,
+		"timeadd":         stdlib.TimeAddFunc,
+		"title":           stdlib.TitleFunc,
+		"trim":            stdlib.TrimFunc,
+		"trimprefix":      stdlib.TrimPrefixFunc,
+		"trimspace":       stdlib.TrimSpaceFunc,
+		"trimsuffix":      stdlib.TrimSuffixFunc,
+		"upper":           stdlib.UpperFunc,
+		"values":          stdlib.ValuesFunc,
+		"zipmap":          stdlib.ZipmapFunc,
+	}
+}
@@ -0,0 +1,225 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Terraform's JSON syntax has no way to tell blocks apart from object attributes without knowing
+// the schema of the file. The schemas below cover the blocks defined by Terraform itself; any other
+// nested object (including provider specific nested blocks) is represented as an object attribute.
+var (
+	// terraformSchema is the schema of the top level of a terraform file.
+	terraformSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "resource", LabelNames: []string{"type", "name"}},
+			{Type: "data", LabelNames: []string{"type", "name"}},
+			{Type: "module", LabelNames: []string{"name"}},
+			{Type: "provider", LabelNames: []string{"name"}},
+			{Type: "variable", LabelNames: []string{"name"}},
+			{Type: "output", LabelNames: []string{"name"}},
+			{Type: "locals"},
+			{Type: "terraform"},
+			{Type: "moved"},
+		},
+	}
+
+	// nestedSchemas are the schemas of the blocks that can appear within each block type.
+	nestedSchemas = map[string]*hcl.BodySchema{
+		"resource": resourceSchema,
+		"data":     resourceSchema,
+		"variable": {Blocks: []hcl.BlockHeaderSchema{{Type: "validation"}}},
+		"terraform": {Blocks: []hcl.BlockHeaderSchema{
+			{Type: "backend", LabelNames: []string{"type"}},
+			{Type: "required_providers"},
+			{Type: "cloud"},
+		}},
+		"provisioner": {Blocks: []hcl.BlockHeaderSchema{{Type: "connection"}}},
+		"dynamic":     {Blocks: []hcl.BlockHeaderSchema{{Type: "content"}}},
+	}
+
+	resourceSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "lifecycle"},
+			{Type: "connection"},
+			{Type: "provisioner", LabelNames: []string{"type"}},
+			{Type: "dynamic", LabelNames: []string{"name"}},
+		},
+	}
+)
+
+// IsJSON returns whether the file content uses Terraform's JSON syntax. A native syntax file can
+// never start with an opening brace, so there's no need to rely on the file name.
+func IsJSON(content []byte) bool {
+	trimmed := bytes.TrimSpace(content)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// parseJSON parses a file in Terraform's JSON syntax and returns it in the same structure as a
+// native syntax file would be parsed into. This allows rules to handle both syntaxes the same way.
+// All ranges point into the JSON source.
+func parseJSON(content []byte, filename string) *hclsyntax.Body {
+	file, _ := hclparse.NewParser().ParseJSON(content, filename)
+	if file == nil {
+		return &hclsyntax.Body{Attributes: hclsyntax.Attributes{}}
+	}
+
+	converter := &jsonConverter{content: content, filename: filename}
+
+	start := hcl.Pos{Line: 1, Column: 1, Byte: 0}
+	end := file.Body.MissingItemRange()
+
+	return converter.body(file.Body, terraformSchema, hcl.Range{Filename: filename, Start: start, End: end.End})
+}
+
+// jsonConverter converts the parts of a JSON file into their native syntax equivalent.
+type jsonConverter struct {
+	content  []byte
+	filename string
+}
+
+// body converts a JSON body, treating the blocks in the given schema as blocks and everything else
+// as attributes.
+func (c *jsonConverter) body(body hcl.Body, schema *hcl.BodySchema, srcRange hcl.Range) *hclsyntax.Body {
+	converted := &hclsyntax.Body{
+		Attributes: hclsyntax.Attributes{},
+		Blocks:     hclsyntax.Blocks{},
+		SrcRange:   srcRange,
+		EndRange:   hcl.Range{Filename: srcRange.Filename, Start: srcRange.End, End: srcRange.End},
+	}
+
+	if schema == nil {
+		schema = &hcl.BodySchema{}
+	}
+
+	content, remain, _ := body.PartialContent(schema)
+	if content != nil {
+		for _, block := range content.Blocks {
+			converted.Blocks = append(converted.Blocks, c.block(block))
+		}
+	}
+
+	attributes, _ := remain.JustAttributes()
+	for name, attribute := range attributes {
+		expr := c.expression(attribute.Expr)
+
+		converted.Attributes[name] = &hclsyntax.Attribute{
+			Name:      name,
+			Expr:      expr,
+			SrcRange:  attribute.Range,
+			NameRange: attribute.NameRange,
+			EqualsRange: hcl.Range{
+				Filename: c.filename,
+				Start:    attribute.NameRange.End,
+				End:      expr.Range().Start,
+			},
+		}
+	}
+
+	return converted
+}
+
+// block converts a JSON block. The opening brace of the block's object stands in for the opening
+// brace of a native block and so on.
+func (c *jsonConverter) block(block *hcl.Block) *hclsyntax.Block {
+	openRange := block.DefRange
+	closeRange := block.Body.MissingItemRange()
+
+	return &hclsyntax.Block{
+		Type:            block.Type,
+		Labels:          block.Labels,
+		Body:            c.body(block.Body, nestedSchemas[block.Type], hcl.RangeBetween(openRange, closeRange)),
+		TypeRange:       block.TypeRange,
+		LabelRanges:     block.LabelRanges,
+		OpenBraceRange:  openRange,
+		CloseBraceRange: closeRange,
+	}
+}
+
+// expression converts a JSON value into the expression it represents. Strings are parsed as
+// templates, just like terraform does.
+func (c *jsonConverter) expression(expr hcl.Expression) hclsyntax.Expression {
+	srcRange := expr.Range()
+
+	source := []byte{}
+	if srcRange.Start.Byte >= 0 && srcRange.End.Byte <= len(c.content) && srcRange.Start.Byte < srcRange.End.Byte {
+		source = c.content[srcRange.Start.Byte:srcRange.End.Byte]
+	}
+
+	if len(source) == 0 {
+		return &hclsyntax.LiteralValueExpr{Val: cty.NullVal(cty.DynamicPseudoType), SrcRange: srcRange}
+	}
+
+	switch source[0] {
+	case '{':
+		items := []hclsyntax.ObjectConsItem{}
+		pairs, _ := hcl.ExprMap(expr)
+		for _, pair := range pairs {
+			items = append(items, hclsyntax.ObjectConsItem{
+				KeyExpr:   &hclsyntax.ObjectConsKeyExpr{Wrapped: c.expression(pair.Key)},
+				ValueExpr: c.expression(pair.Value),
+			})
+		}
+
+		return &hclsyntax.ObjectConsExpr{
+			Items:     items,
+			SrcRange:  srcRange,
+			OpenRange: hcl.Range{Filename: c.filename, Start: srcRange.Start, End: nextPos(srcRange.Start)},
+		}
+
+	case '[':
+		exprs := []hclsyntax.Expression{}
+		values, _ := hcl.ExprList(expr)
+		for _, value := range values {
+			exprs = append(exprs, c.expression(value))
+		}
+
+		return &hclsyntax.TupleConsExpr{
+			Exprs:     exprs,
+			SrcRange:  srcRange,
+			OpenRange: hcl.Range{Filename: c.filename, Start: srcRange.Start, End: nextPos(srcRange.Start)},
+		}
+
+	case '"':
+		var str string
+		err := json.Unmarshal(source, &str)
+		if err != nil {
+			return &hclsyntax.LiteralValueExpr{Val: cty.StringVal(string(source)), SrcRange: srcRange}
+		}
+
+		// Positions within the template are only exact for strings without escape sequences.
+		template, diags := hclsyntax.ParseTemplate([]byte(str), c.filename, nextPos(srcRange.Start))
+		if diags.HasErrors() {
+			return &hclsyntax.LiteralValueExpr{Val: cty.StringVal(str), SrcRange: srcRange}
+		}
+
+		return template
+
+	case 't':
+		return &hclsyntax.LiteralValueExpr{Val: cty.True, SrcRange: srcRange}
+
+	case 'f':
+		return &hclsyntax.LiteralValueExpr{Val: cty.False, SrcRange: srcRange}
+
+	case 'n':
+		return &hclsyntax.LiteralValueExpr{Val: cty.NullVal(cty.DynamicPseudoType), SrcRange: srcRange}
+
+	default:
+		number, err := cty.ParseNumberVal(string(source))
+		if err != nil {
+			return &hclsyntax.LiteralValueExpr{Val: cty.NullVal(cty.DynamicPseudoType), SrcRange: srcRange}
+		}
+
+		return &hclsyntax.LiteralValueExpr{Val: number, SrcRange: srcRange}
+	}
+}
+
+// nextPos returns the position of the character after the given single byte character.
+func nextPos(pos hcl.Pos) hcl.Pos {
+	return hcl.Pos{Line: pos.Line, Column: pos.Column + 1, Byte: pos.Byte + 1}
+}
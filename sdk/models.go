@@ -2,7 +2,14 @@
 // It provides the primitives to allow for ruleset/rule creation and structs to help in parsing tfvet output.
 package sdk
 
-import "github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	"github.com/hashicorp/go-hclog"
+)
 
 // Ruleset represents a packaged set of rules that govern what tfvet checks for.
 type Ruleset struct {
@@ -22,12 +29,62 @@ type Check interface {
 	Check(content []byte) ([]RuleError, error)
 }
 
+// ModuleCheck is a check that also receives the module the linted file belongs to. This allows
+// the check to evaluate expressions that refer to input variables and local values declared
+// anywhere in the module.
+//
+// content is the full hclfile in byte format.
+type ModuleCheck interface {
+	CheckModule(content []byte, module *Module) ([]RuleError, error)
+}
+
+// ContextCheck is a check that receives a context along with everything there is to know about the
+// linted file. The context is cancelled once tfvet stops waiting for the rule, for example because
+// the rule ran out of time; long running checks should stop once it is done.
+type ContextCheck interface {
+	CheckWithContext(ctx context.Context, request *CheckRequest) ([]RuleError, error)
+}
+
+// CheckRequest is the file a ContextCheck lints.
+type CheckRequest struct {
+	// Filepath is the path of the linted file, as given to tfvet.
+	Filepath string
+	// Kind is the kind of the linted file.
+	Kind FileKind
+	// Content is the full file in byte format.
+	Content []byte
+	// Module is the module the linted file belongs to.
+	Module *Module
+	// Logger writes to tfvet's log, named after the ruleset and rule. Logs are shown depending on the
+	// level tfvet is run with (--log-level or TFVET_LOG).
+	Logger hclog.Logger
+}
+
+// PlanCheck is a check that lints terraform plans instead of files. Plans hold the final values of
+// resources after modules, count and for_each are expanded, which can't be known from the files
+// alone.
+//
+// Errors should set Address to the address of the resource they were found in.
+type PlanCheck interface {
+	CheckPlan(plan *Plan) ([]RuleError, error)
+}
+
 // Rule is the representation of a single rule within tfvet.
 // This just combines the rule with the check interface.
 // This should be kept in lockstep with the Rule model from the tfvet package.
 type Rule struct {
-	// ID is used by the main tfvet program to uniquely identify rules. Should not be set if creating a rule.
+	// ID is used by the main tfvet program to uniquely identify rules. It should be a short,
+	// alphanumeric identifier that never changes once published (for example "AWS001"), since users
+	// refer to rules by it. If left empty, tfvet derives an ID from the rule's directory name;
+	// such IDs change whenever the directory is renamed.
 	ID string `hcl:"id,label" json:"id"`
+	// Aliases are previous IDs the rule was known by, so that references to them still resolve.
+	// Managed by tfvet; should not be set if creating a rule.
+	Aliases []string `hcl:"aliases,optional" json:"aliases,omitempty"`
+	// FileOnly is set for rules that only look at the file they lint, not the rest of its module.
+	// tfvet doesn't send these rules the module. Managed by tfvet; should not be set if creating a
+	// rule.
+	FileOnly bool `hcl:"file_only,optional" json:"file_only,omitempty"`
 	// The name of the rule, it should be short and to the point of what the rule is for.
 	Name string `hcl:"name" json:"name"`
 	// A short description about the rule. This should be one line at most and will be shown
@@ -40,9 +97,104 @@ type Rule struct {
 	// Enabled controls whether the rule will be enabled by default on addition of a ruleset.
 	// If enabled is set to false, the user will have to manually turn on the rule.
 	Enabled bool `hcl:"enabled" json:"enabled"`
+	// FileKinds are the kinds of files the rule should be run against. If left empty the rule is
+	// only run against configuration files.
+	FileKinds []FileKind `hcl:"file_kinds,optional" json:"file_kinds,omitempty"`
+	// Category is the kind of problem the rule finds. Users can leave out whole categories of
+	// rules when linting.
+	Category Category `hcl:"category,optional" json:"category,omitempty"`
+	// Tags are free-form labels users can select rules by when linting, for example "s3" or "pci".
+	Tags []string `hcl:"tags,optional" json:"tags,omitempty"`
+	// Providers are the terraform providers the rule targets, for example "aws" or "google". The
+	// rule is only run against modules that use at least one of them, which saves running rules that
+	// can't find anything. If left empty the rule is run against every module.
+	Providers []string `hcl:"providers,optional" json:"providers,omitempty"`
+	// GoodExamples are snippets of terraform the rule finds no errors in, showing users what
+	// compliant code looks like. They are shown by "tfvet rule describe" and checked by
+	// "tfvet rule test".
+	GoodExamples []string `hcl:"good_examples,optional" json:"good_examples,omitempty"`
+	// BadExamples are snippets of terraform the rule finds at least one error in.
+	BadExamples []string `hcl:"bad_examples,optional" json:"bad_examples,omitempty"`
 	// Check is a function which runs when the rule is called. This should contain the logic around
 	// what the rule is checking.
 	Check `json:"-"`
+	// ModuleCheck can be set instead of Check for rules that need the module the linted file
+	// belongs to.
+	ModuleCheck ModuleCheck `json:"-"`
+	// ContextCheck can be set instead of Check or ModuleCheck for rules that need to observe
+	// cancellation, want to log, or need to know more about the linted file.
+	ContextCheck ContextCheck `json:"-"`
+	// PlanCheck is run when linting terraform plans. It can be set along with, or instead of,
+	// Check or ModuleCheck.
+	PlanCheck PlanCheck `json:"-"`
+
+	// logger is set when the rule is served as a plugin and logs to the tfvet process.
+	logger hclog.Logger
+}
+
+// Category is the kind of problem a rule finds.
+type Category string
+
+const (
+	// CategorySecurity rules find configuration that leaves infrastructure open to attack.
+	CategorySecurity Category = "security"
+	// CategoryCost rules find configuration that costs more than it needs to.
+	CategoryCost Category = "cost"
+	// CategoryStyle rules find configuration that works, but is harder to read or maintain.
+	CategoryStyle Category = "style"
+	// CategoryCorrectness rules find configuration that doesn't do what it was meant to, or fails.
+	CategoryCorrectness Category = "correctness"
+)
+
+// Categories are all categories a rule can be in.
+var Categories = []Category{CategorySecurity, CategoryCost, CategoryStyle, CategoryCorrectness}
+
+// IsValid returns whether the category is one of the known categories.
+func (category Category) IsValid() bool {
+	for _, known := range Categories {
+		if category == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FileKind is a kind of terraform file that rules can be run against.
+type FileKind string
+
+const (
+	// Configuration files (.tf and .tf.json) declare resources, modules, variables and so on.
+	Configuration FileKind = "configuration"
+	// VariableDefinitions files (.tfvars) set the values of input variables.
+	VariableDefinitions FileKind = "variable_definitions"
+	// TerraformPlan files are terraform plans in JSON format. Rules are run against them when they have a
+	// PlanCheck; this kind is added by the sdk and shouldn't be listed in FileKinds.
+	TerraformPlan FileKind = "plan"
+)
+
+// FileKindOf returns the kind of the terraform file at the given path.
+func FileKindOf(path string) FileKind {
+	if strings.HasSuffix(path, ".tfvars") {
+		return VariableDefinitions
+	}
+
+	return Configuration
+}
+
+// AppliesTo returns whether the rule should be run against files of the given kind.
+func (rule *Rule) AppliesTo(kind FileKind) bool {
+	if len(rule.FileKinds) == 0 {
+		return kind == Configuration
+	}
+
+	for _, fileKind := range rule.FileKinds {
+		if fileKind == kind {
+			return true
+		}
+	}
+
+	return false
 }
 
 // Position represents location within a document.
@@ -70,6 +222,57 @@ type RuleError struct {
 	// that can be used by any tooling consuming said rule. For example "severity"
 	// might be something included in metadata.
 	Metadata map[string]string `json:"metadata"`
+	// Address is the address of the resource the error was found in. Set by plan checks, which
+	// find errors in resources rather than files; tfvet uses it to point the error at the
+	// resource's configuration when no location is given.
+	Address string `json:"address,omitempty"`
+}
+
+// DiagnosticSeverity is how serious a problem a rule ran into is.
+type DiagnosticSeverity string
+
+const (
+	// DiagnosticError means the rule could not check all of the file, so the lint errors it
+	// returned may be incomplete.
+	DiagnosticError DiagnosticSeverity = "error"
+	// DiagnosticWarning means the rule checked the file but something is worth pointing out, like a
+	// construct the rule doesn't understand and skipped.
+	DiagnosticWarning DiagnosticSeverity = "warning"
+)
+
+// Diagnostic is a problem a rule ran into while linting, as opposed to a lint error it found.
+type Diagnostic struct {
+	Severity DiagnosticSeverity `json:"severity"`
+	Message  string             `json:"message"`
+	// Location is where in the file the problem occurred. Nil if it isn't tied to a specific part
+	// of the file.
+	Location *Range `json:"location,omitempty"`
+}
+
+// Diagnostics can be returned as the error of a check to report warnings, or several problems at
+// once, along with the lint errors found. Any other error returned by a check is reported as a
+// single error diagnostic. Either way, the lint errors returned along with the error are kept.
+type Diagnostics []Diagnostic
+
+// Error returns the messages of all diagnostics.
+func (d Diagnostics) Error() string {
+	messages := []string{}
+	for _, diagnostic := range d {
+		messages = append(messages, fmt.Sprintf("%s: %s", diagnostic.Severity, diagnostic.Message))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// HasErrors returns whether any of the diagnostics is an error.
+func (d Diagnostics) HasErrors() bool {
+	for _, diagnostic := range d {
+		if diagnostic.Severity == DiagnosticError {
+			return true
+		}
+	}
+
+	return false
 }
 
 // LintErrorWrapper is a convenience struct so that json output is easier to programmatically read.
@@ -107,15 +310,41 @@ func ProtoToRuleError(proto *proto.RuleError) *RuleError {
 	re.Suggestion = proto.Suggestion
 	re.Remediation = proto.Remediation
 	re.Metadata = proto.Metadata
+	re.Address = proto.Address
+	// Rules may leave out the location, making the error apply to the file as a whole.
+	location := proto.GetLocation()
 	re.Location = Range{
 		Start: Position{
-			Line:   proto.Location.Start.Line,
-			Column: proto.Location.Start.Column,
+			Line:   location.GetStart().GetLine(),
+			Column: location.GetStart().GetColumn(),
 		},
 		End: Position{
-			Line:   proto.Location.End.Line,
-			Column: proto.Location.End.Column,
+			Line:   location.GetEnd().GetLine(),
+			Column: location.GetEnd().GetColumn(),
 		},
 	}
 	return re
 }
+
+// ProtoToDiagnostic converts a diagnostic received from a rule.
+func ProtoToDiagnostic(proto *proto.Diagnostic) *Diagnostic {
+	diagnostic := &Diagnostic{
+		Severity: DiagnosticSeverity(proto.Severity),
+		Message:  proto.Message,
+	}
+
+	if proto.Location != nil {
+		diagnostic.Location = &Range{
+			Start: Position{
+				Line:   proto.Location.GetStart().GetLine(),
+				Column: proto.Location.GetStart().GetColumn(),
+			},
+			End: Position{
+				Line:   proto.Location.GetEnd().GetLine(),
+				Column: proto.Location.GetEnd().GetColumn(),
+			},
+		}
+	}
+
+	return diagnostic
+}
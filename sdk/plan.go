@@ -0,0 +1,97 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Plan is a terraform plan, as produced by `terraform show -json`.
+type Plan struct {
+	// TerraformVersion is the version of terraform that created the plan.
+	TerraformVersion string `json:"terraform_version"`
+	// Resources are all resources that exist once the plan is applied, including those of child
+	// modules. Resources the plan destroys aren't included.
+	Resources []PlanResource `json:"resources"`
+}
+
+// PlanResource is a single resource instance within a plan.
+type PlanResource struct {
+	// Address is the full address of the resource instance, for example
+	// "module.network.aws_subnet.private[0]".
+	Address string `json:"address"`
+	// ModuleAddress is the address of the module the resource is in; empty for the root module.
+	ModuleAddress string `json:"module_address,omitempty"`
+	// Mode is "managed" for resources and "data" for data sources.
+	Mode string `json:"mode"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Index is the count or for_each key of the resource instance, if any.
+	Index interface{} `json:"index,omitempty"`
+	// ProviderName is the source address of the provider of the resource, for example
+	// "registry.terraform.io/hashicorp/aws".
+	ProviderName string `json:"provider_name"`
+	// Values are the attributes of the resource once the plan is applied. Attributes that are
+	// only known after apply are left out.
+	Values map[string]interface{} `json:"values"`
+	// Actions are the actions terraform takes on the resource, for example ["create"] or
+	// ["no-op"].
+	Actions []string `json:"actions"`
+}
+
+// planJSON is the part of terraform's JSON plan format that is handed to rules.
+type planJSON struct {
+	FormatVersion    string `json:"format_version"`
+	TerraformVersion string `json:"terraform_version"`
+	PlannedValues    struct {
+		RootModule planModuleJSON `json:"root_module"`
+	} `json:"planned_values"`
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+type planModuleJSON struct {
+	Address      string           `json:"address"`
+	Resources    []PlanResource   `json:"resources"`
+	ChildModules []planModuleJSON `json:"child_modules"`
+}
+
+// ParsePlan parses a terraform plan in the JSON format produced by `terraform show -json`.
+func ParsePlan(content []byte) (*Plan, error) {
+	raw := planJSON{}
+	err := json.Unmarshal(content, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw.FormatVersion == "" {
+		return nil, errors.New("not a terraform plan in JSON format; create one with `terraform show -json <planfile>`")
+	}
+
+	actions := map[string][]string{}
+	for _, change := range raw.ResourceChanges {
+		actions[change.Address] = change.Change.Actions
+	}
+
+	plan := &Plan{
+		TerraformVersion: raw.TerraformVersion,
+		Resources:        []PlanResource{},
+	}
+
+	modules := []planModuleJSON{raw.PlannedValues.RootModule}
+	for len(modules) > 0 {
+		module := modules[0]
+		modules = append(modules[1:], module.ChildModules...)
+
+		for _, resource := range module.Resources {
+			resource.ModuleAddress = module.Address
+			resource.Actions = actions[resource.Address]
+			plan.Resources = append(plan.Resources, resource)
+		}
+	}
+
+	return plan, nil
+}
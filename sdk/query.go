@@ -0,0 +1,142 @@
+package sdk
+
+import (
+	"reflect"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Node is anything within a parsed file that has a location, like blocks, attributes and
+// expressions.
+type Node interface {
+	Range() hcl.Range
+}
+
+// Blocks returns the blocks of the given type defined directly within body. If labels are given
+// only blocks whose leading labels match them are returned.
+//
+//	Blocks(body, "module")
+//	Blocks(body, "resource", "aws_s3_bucket")
+func Blocks(body *hclsyntax.Body, blockType string, labels ...string) []*hclsyntax.Block {
+	blocks := []*hclsyntax.Block{}
+
+	for _, block := range body.Blocks {
+		if block.Type != blockType || len(block.Labels) < len(labels) {
+			continue
+		}
+
+		matches := true
+		for index, label := range labels {
+			if block.Labels[index] != label {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+// Resources returns the resource blocks of the given resource type, like "aws_s3_bucket". An
+// empty resource type returns all resource blocks.
+func Resources(body *hclsyntax.Body, resourceType string) []*hclsyntax.Block {
+	if resourceType == "" {
+		return Blocks(body, "resource")
+	}
+
+	return Blocks(body, "resource", resourceType)
+}
+
+// DataSources returns the data blocks of the given data source type. An empty data source type
+// returns all data blocks.
+func DataSources(body *hclsyntax.Body, dataSourceType string) []*hclsyntax.Block {
+	if dataSourceType == "" {
+		return Blocks(body, "data")
+	}
+
+	return Blocks(body, "data", dataSourceType)
+}
+
+// NestedBlocks returns the blocks of the given type defined directly within block, like the
+// "ingress" blocks of a security group.
+func NestedBlocks(block *hclsyntax.Block, blockType string) []*hclsyntax.Block {
+	return Blocks(block.Body, blockType)
+}
+
+// Attribute returns the attribute with the given name defined directly within block or nil if
+// the block does not set it.
+func Attribute(block *hclsyntax.Block, name string) *hclsyntax.Attribute {
+	attribute, ok := block.Body.Attributes[name]
+	if !ok {
+		return nil
+	}
+
+	return attribute
+}
+
+// WalkBlocks calls fn for every block within body, including blocks nested in other blocks.
+// Blocks are visited in the order they are defined, parents before their children. The parents
+// of each block, outermost first, are passed along with it.
+func WalkBlocks(body *hclsyntax.Body, fn func(block *hclsyntax.Block, parents []*hclsyntax.Block)) {
+	walkBlocks(body, []*hclsyntax.Block{}, fn)
+}
+
+func walkBlocks(body *hclsyntax.Body, parents []*hclsyntax.Block,
+	fn func(block *hclsyntax.Block, parents []*hclsyntax.Block)) {
+	for _, block := range body.Blocks {
+		fn(block, parents)
+
+		// Copy so that callers keeping parents around don't see them change underneath them.
+		children := make([]*hclsyntax.Block, len(parents), len(parents)+1)
+		copy(children, parents)
+		walkBlocks(block.Body, append(children, block), fn)
+	}
+}
+
+// RangeFromHCL converts a range within a parsed file into the range lint errors are reported with.
+func RangeFromHCL(hclRange hcl.Range) Range {
+	return Range{
+		Start: Position{Line: uint32(hclRange.Start.Line), Column: uint32(hclRange.Start.Column)},
+		End:   Position{Line: uint32(hclRange.End.Line), Column: uint32(hclRange.End.Column)},
+	}
+}
+
+// Report returns a lint error with the given suggestion located at node. Blocks are located by
+// their definition, the line with the block type and labels, rather than their entire body.
+//
+//	lintErrors = append(lintErrors, tfvet.Report(bucket, "S3 buckets should be tagged"))
+//
+// A nil node, like what Attribute returns for an attribute that isn't set, results in a lint
+// error without a location, which is reported against the file as a whole. Report the block
+// instead to point users at where to add the attribute.
+func Report(node Node, suggestion string) RuleError {
+	if isNil(node) {
+		return RuleError{Suggestion: suggestion}
+	}
+
+	nodeRange := node.Range()
+	if block, ok := node.(*hclsyntax.Block); ok {
+		nodeRange = block.DefRange()
+	}
+
+	return RuleError{
+		Suggestion: suggestion,
+		Location:   RangeFromHCL(nodeRange),
+	}
+}
+
+// isNil returns whether node is nil, including nil pointers of a type implementing Node, which
+// don't compare equal to nil once passed as a Node.
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+
+	value := reflect.ValueOf(node)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
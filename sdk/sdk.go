@@ -1,10 +1,17 @@
 package sdk
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"regexp"
+	"strings"
 
 	tfvetPlugin "github.com/clintjedwards/tfvet/v2/internal/plugin"
 	proto "github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -14,33 +21,133 @@ import (
 func (rule *Rule) GetRuleInfo(request *proto.GetRuleInfoRequest) (*proto.GetRuleInfoResponse, error) {
 	ruleInfo := proto.GetRuleInfoResponse{
 		RuleInfo: &proto.RuleInfo{
-			Name:    rule.Name,
-			Short:   rule.Short,
-			Long:    rule.Long,
-			Link:    rule.Link,
-			Enabled: rule.Enabled,
+			Id:           rule.ID,
+			Name:         rule.Name,
+			Short:        rule.Short,
+			Long:         rule.Long,
+			Link:         rule.Link,
+			Enabled:      rule.Enabled,
+			Category:     string(rule.Category),
+			Tags:         rule.Tags,
+			Providers:    rule.Providers,
+			GoodExamples: rule.GoodExamples,
+			BadExamples:  rule.BadExamples,
+			FileOnly:     rule.ModuleCheck == nil && rule.ContextCheck == nil,
 		},
 	}
 
+	for _, kind := range rule.fileKinds() {
+		ruleInfo.RuleInfo.FileKinds = append(ruleInfo.RuleInfo.FileKinds, string(kind))
+	}
+
 	return &ruleInfo, nil
 }
 
+// ListRules returns information about the rule itself; a single rule plugin only serves one rule.
+func (rule *Rule) ListRules(request *proto.ListRulesRequest) (*proto.ListRulesResponse, error) {
+	ruleInfo, err := rule.GetRuleInfo(&proto.GetRuleInfoRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.ListRulesResponse{
+		Rules: []*proto.RuleInfo{ruleInfo.RuleInfo},
+	}, nil
+}
+
 // ExecuteRule runs the linting rule given a single file and returns any linting errors.
-func (rule *Rule) ExecuteRule(request *proto.ExecuteRuleRequest) (*proto.ExecuteRuleResponse, error) {
-	ruleErrors, err := rule.Check.Check(request.HclFile)
+func (rule *Rule) ExecuteRule(ctx context.Context, request *proto.ExecuteRuleRequest) (*proto.ExecuteRuleResponse, error) {
+	var ruleErrors []RuleError
+	var err error
+
+	files := request.ModuleFiles
+	if len(files) == 0 {
+		files = map[string][]byte{"main.tf": request.HclFile}
+	}
+
+	if rule.ContextCheck != nil {
+		ruleErrors, err = rule.ContextCheck.CheckWithContext(ctx, &CheckRequest{
+			Filepath: request.Filepath,
+			Kind:     FileKindOf(request.Filepath),
+			Content:  request.HclFile,
+			Module:   NewModule(files),
+			Logger:   rule.getLogger(ctx),
+		})
+	} else if rule.ModuleCheck != nil {
+		ruleErrors, err = rule.ModuleCheck.CheckModule(request.HclFile, NewModule(files))
+	} else if rule.Check != nil {
+		ruleErrors, err = rule.Check.Check(request.HclFile)
+	} else {
+		return nil, errors.New("rule does not lint files")
+	}
+
+	return &proto.ExecuteRuleResponse{
+		Errors:      ruleErrorsToProto(ruleErrors),
+		Diagnostics: diagnosticsToProto(err),
+	}, nil
+}
+
+// ExecutePlanRule runs the linting rule given a terraform plan and returns any linting errors.
+func (rule *Rule) ExecutePlanRule(ctx context.Context, request *proto.ExecutePlanRuleRequest) (*proto.ExecuteRuleResponse, error) {
+	if rule.PlanCheck == nil {
+		return nil, errors.New("rule does not lint plans")
+	}
+
+	plan, err := ParsePlan(request.Plan)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleErrors, err := rule.PlanCheck.CheckPlan(plan)
 
 	return &proto.ExecuteRuleResponse{
-		Errors: ruleErrorsToProto(ruleErrors),
-	}, err
+		Errors:      ruleErrorsToProto(ruleErrors),
+		Diagnostics: diagnosticsToProto(err),
+	}, nil
+}
+
+// getLogger returns the logger the rule logs to. Rules served as plugins log to the tfvet process;
+// rules run in-process use the logger tfvet passes along in the context.
+func (rule *Rule) getLogger(ctx context.Context) hclog.Logger {
+	if rule.logger != nil {
+		return rule.logger
+	}
+
+	return hclog.FromContext(ctx)
+}
+
+// fileKinds returns the kinds of files the rule is run against. Rules with a plan check are also
+// run against plans.
+func (rule *Rule) fileKinds() []FileKind {
+	kinds := append([]FileKind{}, rule.FileKinds...)
+
+	if rule.PlanCheck == nil {
+		return kinds
+	}
+
+	if len(kinds) == 0 && (rule.Check != nil || rule.ModuleCheck != nil || rule.ContextCheck != nil) {
+		kinds = append(kinds, Configuration)
+	}
+
+	return append(kinds, TerraformPlan)
 }
 
 // ParseHCL parses the HCL file content and returns a simple data structure representing the file.
 // It's safe to ignore the error from ParseHCL as it should have already been handled by the main
 // process.
+//
+// Files written in Terraform's JSON syntax (.tf.json) are returned in the same structure as native
+// syntax files, so rules don't need to handle them separately. Blocks defined by Terraform itself
+// (resource, lifecycle, provisioner and so on) are returned as blocks, any other JSON objects are
+// returned as object attributes. Ranges point into the JSON source.
 func ParseHCL(content []byte) *hclsyntax.Body {
 	//TODO(clintjedwards): Having to reparse the file for every plugin is very slow, figure
 	// out if there is a better way to transfer this information to the main binary and have
 	// plugins consume that instead.
+	if IsJSON(content) {
+		return parseJSON(content, "tmp")
+	}
+
 	parser := hclparse.NewParser()
 	file, _ := parser.ParseHCL(content, "tmp")
 	return file.Body.(*hclsyntax.Body)
@@ -64,12 +171,52 @@ func ruleErrorsToProto(ruleErrors []RuleError) []*proto.RuleError {
 			Suggestion:  ruleError.Suggestion,
 			Remediation: ruleError.Remediation,
 			Metadata:    ruleError.Metadata,
+			Address:     ruleError.Address,
 		})
 	}
 
 	return protoRuleErrors
 }
 
+// diagnosticsToProto converts the error returned by a check into diagnostics. Errors that aren't
+// Diagnostics become a single error diagnostic.
+func diagnosticsToProto(err error) []*proto.Diagnostic {
+	if err == nil {
+		return nil
+	}
+
+	var diagnostics Diagnostics
+	if !errors.As(err, &diagnostics) {
+		diagnostics = Diagnostics{{Severity: DiagnosticError, Message: err.Error()}}
+	}
+
+	protoDiagnostics := []*proto.Diagnostic{}
+
+	for _, diagnostic := range diagnostics {
+		protoDiagnostic := &proto.Diagnostic{
+			Severity: string(diagnostic.Severity),
+			Message:  diagnostic.Message,
+		}
+
+		if diagnostic.Location != nil {
+			protoDiagnostic.Location = &proto.Location{
+				Start: &proto.Position{
+					Line:   diagnostic.Location.Start.Line,
+					Column: diagnostic.Location.Start.Column,
+				},
+				End: &proto.Position{
+					Line:   diagnostic.Location.End.Line,
+					Column: diagnostic.Location.End.Column,
+				},
+			}
+		}
+
+		protoDiagnostics = append(protoDiagnostics, protoDiagnostic)
+	}
+
+	return protoDiagnostics
+}
+
 // validates a new rule has at least the basic information
 func (rule *Rule) isValid() bool {
 	if rule.Short == "" {
@@ -80,20 +227,48 @@ func (rule *Rule) isValid() bool {
 		return false
 	}
 
-	if rule.Check == nil {
+	if rule.Check == nil && rule.ModuleCheck == nil && rule.ContextCheck == nil && rule.PlanCheck == nil {
+		return false
+	}
+
+	if rule.ID != "" && !IsValidRuleID(rule.ID) {
+		return false
+	}
+
+	for _, kind := range rule.FileKinds {
+		if kind != Configuration && kind != VariableDefinitions {
+			return false
+		}
+	}
+
+	if rule.Category != "" && !rule.Category.IsValid() {
 		return false
 	}
 
 	return true
 }
 
+// ruleIDPattern is the format author defined rule IDs must follow.
+var ruleIDPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,20}$`)
+
+// IsValidRuleID reports whether the given ID is acceptable as an author defined rule ID.
+// IDs must be between 1 and 20 alphanumeric characters.
+func IsValidRuleID(id string) bool {
+	return ruleIDPattern.MatchString(id)
+}
+
 // NewRule registers a new linting rule. This function must be included inside a rule.
+//
+// Rulesets with many rules should consider NewRuleset instead, which serves all of them from a
+// single binary.
 func NewRule(rule *Rule) {
 	if !rule.isValid() {
 		log.Fatalf("%s is not valid", rule.Name)
 		return
 	}
 
+	rule.logger = newPluginLogger(hclog.Trace).Named(rule.ID)
+
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: tfvetPlugin.Handshake,
 		Plugins: map[string]plugin.Plugin{
@@ -101,5 +276,123 @@ func NewRule(rule *Rule) {
 			"tfvet-sdk": &tfvetPlugin.TfvetRulePlugin{Impl: rule},
 		},
 		GRPCServer: plugin.DefaultGRPCServer,
+		Logger:     newPluginLogger(hclog.Warn),
+	})
+}
+
+// newPluginLogger returns a logger for plugins to log to. tfvet reads its output and decides which
+// logs to show. Rules log at any level, while the plugin framework only logs warnings and errors so
+// its details of how plugins are started don't drown out the logs of rules.
+func newPluginLogger(level hclog.Level) hclog.Logger {
+	return hclog.New(&hclog.LoggerOptions{
+		Level:      level,
+		Output:     os.Stderr,
+		JSONFormat: true,
+	})
+}
+
+// rules serves several rules from a single plugin, handing each request to the rule it selects.
+type rules struct {
+	rules map[string]*Rule // keyed by upper case rule ID.
+	order []*Rule
+}
+
+// NewRuleset registers many linting rules that are served by a single binary. Compiling and
+// running one binary is much faster than doing so for every rule separately, which matters for
+// rulesets with many rules. Every rule must declare a unique ID.
+//
+// The binary can live in its own directory within the rules folder, alongside other rules, or
+// the rules folder itself can be the binary's main package.
+func NewRuleset(ruleset ...*Rule) {
+	served := &rules{
+		rules: map[string]*Rule{},
+		order: ruleset,
+	}
+
+	logger := newPluginLogger(hclog.Trace)
+
+	for _, rule := range ruleset {
+		if !rule.isValid() {
+			log.Fatalf("%s is not valid", rule.Name)
+			return
+		}
+
+		if rule.ID == "" {
+			log.Fatalf("%s does not declare an ID; rules served by NewRuleset must declare one", rule.Name)
+			return
+		}
+
+		id := strings.ToUpper(rule.ID)
+		if _, exists := served.rules[id]; exists {
+			log.Fatalf("more than one rule uses the ID %s; rule IDs must be unique", rule.ID)
+			return
+		}
+		served.rules[id] = rule
+		rule.logger = logger.Named(rule.ID)
+	}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: tfvetPlugin.Handshake,
+		Plugins: map[string]plugin.Plugin{
+			"tfvet-sdk": &tfvetPlugin.TfvetRulePlugin{Impl: served},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+		Logger:     newPluginLogger(hclog.Warn),
 	})
 }
+
+// get returns the rule with the given ID.
+func (r *rules) get(id string) (*Rule, error) {
+	rule, ok := r.rules[strings.ToUpper(id)]
+	if !ok {
+		return nil, fmt.Errorf("rule %q is not served by this plugin", id)
+	}
+
+	return rule, nil
+}
+
+// GetRuleInfo returns information about the requested rule.
+func (r *rules) GetRuleInfo(request *proto.GetRuleInfoRequest) (*proto.GetRuleInfoResponse, error) {
+	rule, err := r.get(request.RuleId)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule.GetRuleInfo(request)
+}
+
+// ExecuteRule runs the requested rule given a single file.
+func (r *rules) ExecuteRule(ctx context.Context, request *proto.ExecuteRuleRequest) (*proto.ExecuteRuleResponse, error) {
+	rule, err := r.get(request.RuleId)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule.ExecuteRule(ctx, request)
+}
+
+// ExecutePlanRule runs the requested rule given a terraform plan.
+func (r *rules) ExecutePlanRule(ctx context.Context, request *proto.ExecutePlanRuleRequest) (*proto.ExecuteRuleResponse, error) {
+	rule, err := r.get(request.RuleId)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule.ExecutePlanRule(ctx, request)
+}
+
+// ListRules returns information about all served rules, in the order they were registered.
+func (r *rules) ListRules(request *proto.ListRulesRequest) (*proto.ListRulesResponse, error) {
+	response := &proto.ListRulesResponse{Rules: []*proto.RuleInfo{}}
+
+	for _, rule := range r.order {
+		ruleInfo, err := rule.GetRuleInfo(&proto.GetRuleInfoRequest{})
+		if err != nil {
+			return nil, err
+		}
+
+		response.Rules = append(response.Rules, ruleInfo.RuleInfo)
+	}
+
+	return response, nil
+}
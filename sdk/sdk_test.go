@@ -1,8 +1,13 @@
 package sdk
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 )
 
 func TestLintErrorWrapper(t *testing.T) {
@@ -18,3 +23,354 @@ func TestLintErrorWrapper(t *testing.T) {
 		t.Fatal("LintError which should be an object is nil")
 	}
 }
+
+func TestParseHCLJSON(t *testing.T) {
+	content := []byte(`{
+  "resource": {
+    "google_compute_instance": {
+      "example": {
+        "machine_type": "n1-standard-1",
+        "zone": "${var.zone}",
+        "tags": ["web", "dev"],
+        "lifecycle": {
+          "create_before_destroy": true
+        }
+      }
+    }
+  },
+  "locals": {
+    "count": 3
+  }
+}`)
+
+	body := ParseHCL(content)
+
+	if len(body.Blocks) != 2 {
+		t.Fatalf("expected 2 blocks; got %d", len(body.Blocks))
+	}
+
+	resource := body.Blocks[0]
+	if resource.Type != "resource" || len(resource.Labels) != 2 || resource.Labels[1] != "example" {
+		t.Fatalf("unexpected resource block %s %v", resource.Type, resource.Labels)
+	}
+
+	// The location of a block should point to its definition in the JSON source.
+	defRange := resource.DefRange()
+	if defRange.Start.Line != 2 || defRange.End.Line != 4 {
+		t.Fatalf("unexpected block location %s", defRange)
+	}
+
+	if len(resource.Body.Blocks) != 1 || resource.Body.Blocks[0].Type != "lifecycle" {
+		t.Fatalf("expected a nested lifecycle block; got %v", resource.Body.Blocks)
+	}
+
+	machineType, _ := resource.Body.Attributes["machine_type"].Expr.Value(nil)
+	if machineType.AsString() != "n1-standard-1" {
+		t.Fatalf("unexpected machine_type %s", machineType.GoString())
+	}
+
+	zone := resource.Body.Attributes["zone"]
+	if len(zone.Expr.Variables()) != 1 || zone.SrcRange.Start.Line != 6 {
+		t.Fatalf("expected zone to reference a variable on line 6; got %v at %s",
+			zone.Expr.Variables(), zone.SrcRange)
+	}
+
+	tags, _ := resource.Body.Attributes["tags"].Expr.Value(nil)
+	if tags.LengthInt() != 2 {
+		t.Fatalf("expected 2 tags; got %s", tags.GoString())
+	}
+
+	count, _ := body.Blocks[1].Body.Attributes["count"].Expr.Value(nil)
+	if count.AsBigFloat().String() != "3" {
+		t.Fatalf("unexpected count %s", count.GoString())
+	}
+}
+
+func TestModuleEvaluate(t *testing.T) {
+	content := []byte(`
+resource "google_compute_instance" "example" {
+  machine_type = var.type
+  zone         = local.zone
+  name         = "${local.prefix}-${google_compute_network.example.name}"
+  region       = var.region
+}
+`)
+
+	module := NewModule(map[string][]byte{
+		"main.tf": content,
+		"variables.tf": []byte(`
+variable "type" {
+  default = "n1-standard-1"
+}
+
+variable "region" {}
+
+locals {
+  zone   = "${local.region}-a"
+  region = lower("US-CENTRAL1")
+  prefix = "web"
+}
+`),
+		"terraform.tfvars": []byte(`type = "n1-standard-2"`),
+		"prod.auto.tfvars": []byte(`type = "n1-highmem-2"`),
+		"other.tfvars":     []byte(`type = "n1-standard-4"`),
+	})
+
+	attributes := ParseHCL(content).Blocks[0].Body.Attributes
+
+	machineType := module.Evaluate(attributes["machine_type"].Expr)
+	if machineType.AsString() != "n1-highmem-2" {
+		t.Fatalf("expected machine_type n1-highmem-2; got %#v", machineType)
+	}
+
+	zone := module.Evaluate(attributes["zone"].Expr)
+	if zone.AsString() != "us-central1-a" {
+		t.Fatalf("expected zone us-central1-a; got %#v", zone)
+	}
+
+	for _, name := range []string{"name", "region"} {
+		value := module.Evaluate(attributes[name].Expr)
+		if value.IsWhollyKnown() {
+			t.Fatalf("expected %s to be unknown; got %#v", name, value)
+		}
+	}
+}
+
+func TestParsePlan(t *testing.T) {
+	plan, err := ParsePlan([]byte(`{
+  "format_version": "0.1",
+  "planned_values": {
+    "root_module": {
+      "resources": [{"address": "google_compute_instance.a", "type": "google_compute_instance", "name": "a", "values": {"machine_type": "n1-standard-1"}}],
+      "child_modules": [{
+        "address": "module.vm",
+        "resources": [{"address": "module.vm.google_compute_instance.this[0]", "type": "google_compute_instance", "name": "this", "index": 0}]
+      }]
+    }
+  },
+  "resource_changes": [{"address": "google_compute_instance.a", "change": {"actions": ["create"]}}]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Resources) != 2 {
+		t.Fatalf("expected 2 resources; got %d", len(plan.Resources))
+	}
+
+	if plan.Resources[0].Values["machine_type"] != "n1-standard-1" || plan.Resources[0].Actions[0] != "create" {
+		t.Fatalf("unexpected resource %+v", plan.Resources[0])
+	}
+
+	if plan.Resources[1].ModuleAddress != "module.vm" {
+		t.Fatalf("expected resource in module.vm; got %q", plan.Resources[1].ModuleAddress)
+	}
+
+	_, err = ParsePlan([]byte(`{"resource": {}}`))
+	if err == nil {
+		t.Fatal("expected an error for a file that isn't a plan")
+	}
+}
+
+func TestQueryHelpers(t *testing.T) {
+	body := ParseHCL([]byte(`resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+
+  lifecycle_rule {
+    expiration {
+      days = 30
+    }
+  }
+}
+
+resource "aws_s3_bucket" "assets" {
+  tags = { team = "web" }
+}
+
+resource "aws_instance" "web" {}
+`))
+
+	buckets := Resources(body, "aws_s3_bucket")
+	if len(buckets) != 2 || buckets[1].Labels[1] != "assets" {
+		t.Fatalf("expected 2 buckets; got %v", buckets)
+	}
+
+	if len(Resources(body, "")) != 3 {
+		t.Fatalf("expected 3 resources; got %d", len(Resources(body, "")))
+	}
+
+	if Attribute(buckets[0], "tags") != nil || Attribute(buckets[1], "tags") == nil {
+		t.Fatal("expected only the assets bucket to set tags")
+	}
+
+	if len(NestedBlocks(buckets[0], "lifecycle_rule")) != 1 {
+		t.Fatal("expected a nested lifecycle_rule block")
+	}
+
+	// Nested blocks are visited along with their parents.
+	depths := map[string]int{}
+	WalkBlocks(body, func(block *hclsyntax.Block, parents []*hclsyntax.Block) {
+		depths[block.Type] = len(parents)
+	})
+	if depths["resource"] != 0 || depths["lifecycle_rule"] != 1 || depths["expiration"] != 2 {
+		t.Fatalf("unexpected block depths %v", depths)
+	}
+
+	ruleError := Report(buckets[1], "tag all buckets")
+	if ruleError.Suggestion != "tag all buckets" || ruleError.Location.Start.Line != 11 ||
+		ruleError.Location.End.Line != 11 {
+		t.Fatalf("unexpected rule error %+v", ruleError)
+	}
+
+	ruleError = Report(Attribute(buckets[0], "bucket"), "")
+	if ruleError.Location.Start.Line != 2 || ruleError.Location.Start.Column != 3 {
+		t.Fatalf("unexpected attribute location %+v", ruleError.Location)
+	}
+
+	// Attributes that aren't set are typed nils.
+	ruleError = Report(Attribute(buckets[0], "tags"), "tag all buckets")
+	if ruleError.Suggestion != "tag all buckets" || ruleError.Location != (Range{}) {
+		t.Fatalf("expected a rule error without a location; got %+v", ruleError)
+	}
+}
+
+// partialCheck returns a single lint error along with the given error.
+type partialCheck struct {
+	err error
+}
+
+func (c *partialCheck) Check(content []byte) ([]RuleError, error) {
+	return []RuleError{{Suggestion: "found", Location: Range{Start: Position{Line: 1, Column: 1}}}}, c.err
+}
+
+func TestExecuteRuleDiagnostics(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		severity []string
+	}{
+		"no error":    {err: nil},
+		"plain error": {err: errors.New("could not read block"), severity: []string{"error"}},
+		"diagnostics": {
+			err: Diagnostics{
+				{Severity: DiagnosticWarning, Message: "skipped dynamic block"},
+				{Severity: DiagnosticError, Message: "could not evaluate count",
+					Location: &Range{Start: Position{Line: 2, Column: 3}}},
+			},
+			severity: []string{"warning", "error"},
+		},
+	}
+
+	for name, test := range tests {
+		rule := &Rule{Check: &partialCheck{err: test.err}}
+
+		response, err := rule.ExecuteRule(context.Background(), &proto.ExecuteRuleRequest{})
+		if err != nil {
+			t.Fatalf("%s: errors returned by checks should be reported as diagnostics; got %v", name, err)
+		}
+
+		// The lint errors found should be kept no matter what the check returned along with them.
+		if len(response.Errors) != 1 {
+			t.Fatalf("%s: expected 1 lint error; got %d", name, len(response.Errors))
+		}
+
+		if len(response.Diagnostics) != len(test.severity) {
+			t.Fatalf("%s: expected %d diagnostics; got %d", name, len(test.severity), len(response.Diagnostics))
+		}
+
+		for index, severity := range test.severity {
+			if response.Diagnostics[index].Severity != severity {
+				t.Fatalf("%s: expected diagnostic %d to be an %s; got %s",
+					name, index, severity, response.Diagnostics[index].Severity)
+			}
+		}
+	}
+
+	diagnostic := ProtoToDiagnostic(diagnosticsToProto(tests["diagnostics"].err)[1])
+	if diagnostic.Location == nil || diagnostic.Location.Start.Line != 2 {
+		t.Fatalf("expected the location of the diagnostic to be kept; got %v", diagnostic.Location)
+	}
+}
+
+func TestProtoToRuleErrorLocation(t *testing.T) {
+	tests := map[string]struct {
+		location *proto.Location
+		line     uint32
+	}{
+		"no location": {location: nil},
+		"no start":    {location: &proto.Location{End: &proto.Position{Line: 3, Column: 1}}},
+		"location": {
+			location: &proto.Location{Start: &proto.Position{Line: 2, Column: 3}, End: &proto.Position{Line: 3, Column: 1}},
+			line:     2,
+		},
+	}
+
+	for name, test := range tests {
+		ruleErr := ProtoToRuleError(&proto.RuleError{Suggestion: "fix it", Location: test.location})
+
+		// Errors left without a location by older or third-party rules apply to the whole file.
+		if ruleErr.Location.Start.Line != test.line {
+			t.Errorf("%s: expected error to start at line %d; got %d", name, test.line, ruleErr.Location.Start.Line)
+		}
+	}
+}
+
+func TestIsValidRuleID(t *testing.T) {
+	for _, id := range []string{"AWS001", "a", "89cd4", "ABCDEFGHIJ0123456789"} {
+		if !IsValidRuleID(id) {
+			t.Errorf("expected %q to be a valid rule ID", id)
+		}
+	}
+
+	for _, id := range []string{"", "AWS-001", "aws 001", "../etc", "ABCDEFGHIJ01234567890", "ÄWS001"} {
+		if IsValidRuleID(id) {
+			t.Errorf("expected %q not to be a valid rule ID", id)
+		}
+	}
+}
+
+// suggestionCheck reports a single lint error with the given suggestion.
+type suggestionCheck string
+
+func (c suggestionCheck) Check(content []byte) ([]RuleError, error) {
+	return []RuleError{{Suggestion: string(c)}}, nil
+}
+
+func TestRulesetRouting(t *testing.T) {
+	first := &Rule{ID: "EX001", Name: "First", Check: suggestionCheck("first")}
+	second := &Rule{ID: "EX002", Name: "Second", Check: suggestionCheck("second")}
+	served := &rules{
+		rules: map[string]*Rule{"EX001": first, "EX002": second},
+		order: []*Rule{second, first},
+	}
+
+	info, err := served.GetRuleInfo(&proto.GetRuleInfoRequest{RuleId: "ex001"})
+	if err != nil || info.RuleInfo.Name != "First" {
+		t.Fatalf("expected the info of EX001; got %v, %v", info, err)
+	}
+
+	list, err := served.ListRules(&proto.ListRulesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Rules) != 2 || list.Rules[0].Id != "EX002" || list.Rules[1].Id != "EX001" {
+		t.Fatalf("expected both rules in the order they were registered; got %v", list.Rules)
+	}
+
+	response, err := served.ExecuteRule(context.Background(), &proto.ExecuteRuleRequest{RuleId: "EX002"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Errors) != 1 || response.Errors[0].Suggestion != "second" {
+		t.Fatalf("expected the request to be run by EX002; got %v", response.Errors)
+	}
+
+	if _, err := served.GetRuleInfo(&proto.GetRuleInfoRequest{RuleId: "EX003"}); err == nil {
+		t.Error("expected getting the info of an unknown rule to fail")
+	}
+	if _, err := served.ExecuteRule(context.Background(), &proto.ExecuteRuleRequest{RuleId: "EX003"}); err == nil {
+		t.Error("expected running an unknown rule to fail")
+	}
+	if _, err := served.ExecutePlanRule(context.Background(), &proto.ExecutePlanRuleRequest{RuleId: "EX003"}); err == nil {
+		t.Error("expected running an unknown plan rule to fail")
+	}
+}
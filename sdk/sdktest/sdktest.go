@@ -0,0 +1,136 @@
+// Package sdktest runs tfvet rules from go tests, the same way tfvet runs them when linting.
+//
+// A rule's test usually checks its examples and the files in its testdata directory:
+//
+//	func TestRule(t *testing.T) {
+//		sdktest.TestExamples(t, rule)
+//		sdktest.TestData(t, rule, "testdata")
+//	}
+package sdktest
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/internal/plugin/proto"
+	"github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+// Lint runs the rule against a file and returns the errors it reports. The other files of the
+// module are given by name, and the file itself is added to them. Errors the rule returns fail
+// the test.
+func Lint(t *testing.T, rule *sdk.Rule, filename string, content []byte, module map[string][]byte) []sdk.RuleError {
+	t.Helper()
+
+	files := map[string][]byte{}
+	for name, moduleContent := range module {
+		files[name] = moduleContent
+	}
+	files[filename] = content
+
+	response, err := rule.ExecuteRule(context.Background(), &proto.ExecuteRuleRequest{
+		HclFile:     content,
+		ModuleFiles: files,
+		Filepath:    filename,
+		RuleId:      rule.ID,
+	})
+	if err != nil {
+		t.Fatalf("%s: could not run rule: %v", filename, err)
+	}
+
+	for _, diagnostic := range response.Diagnostics {
+		if diagnostic.Severity == string(sdk.DiagnosticError) {
+			t.Errorf("%s: rule returned an error: %s", filename, diagnostic.Message)
+		}
+	}
+
+	ruleErrors := []sdk.RuleError{}
+	for _, ruleError := range response.Errors {
+		ruleErrors = append(ruleErrors, *sdk.ProtoToRuleError(ruleError))
+	}
+
+	return ruleErrors
+}
+
+// TestExamples checks that the rule reports each of its bad examples and none of its good ones.
+// Every example is linted on its own, as the only file of a module.
+func TestExamples(t *testing.T, rule *sdk.Rule) {
+	t.Helper()
+
+	filename := exampleFilename(rule)
+
+	for index, example := range rule.BadExamples {
+		if len(Lint(t, rule, filename, []byte(example), nil)) == 0 {
+			t.Errorf("bad example %d: expected the rule to report it; it didn't", index+1)
+		}
+	}
+
+	for index, example := range rule.GoodExamples {
+		if ruleErrors := Lint(t, rule, filename, []byte(example), nil); len(ruleErrors) != 0 {
+			t.Errorf("good example %d: expected no lint errors; got %d: %s",
+				index+1, len(ruleErrors), ruleErrors[0].Suggestion)
+		}
+	}
+}
+
+// TestData lints the terraform files in a directory, which make up a single module. Files whose
+// name starts with "bad" are expected to be reported by the rule, those starting with "good" are
+// expected to pass it. Other files are only used as part of the module.
+func TestData(t *testing.T, rule *sdk.Rule, dir string) {
+	t.Helper()
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("could not read test data: %v", err)
+	}
+
+	// Like terraform, only variable definitions files that are loaded automatically are part of
+	// the module; others are just linted.
+	files := map[string][]byte{}
+	module := map[string][]byte{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(sdk.IsModuleFile(name) || strings.HasSuffix(name, ".tfvars")) {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("could not read test data: %v", err)
+		}
+
+		files[name] = content
+		if sdk.IsModuleFile(name) {
+			module[name] = content
+		}
+	}
+
+	if len(files) == 0 {
+		t.Fatalf("no terraform files found in %s", dir)
+	}
+
+	for name, content := range files {
+		if !rule.AppliesTo(sdk.FileKindOf(name)) {
+			continue
+		}
+
+		switch ruleErrors := Lint(t, rule, name, content, module); {
+		case strings.HasPrefix(name, "bad") && len(ruleErrors) == 0:
+			t.Errorf("%s: expected the rule to report it; it didn't", name)
+		case strings.HasPrefix(name, "good") && len(ruleErrors) != 0:
+			t.Errorf("%s: expected no lint errors; got %d: %s", name, len(ruleErrors), ruleErrors[0].Suggestion)
+		}
+	}
+}
+
+// exampleFilename returns the name examples of the rule are linted as.
+func exampleFilename(rule *sdk.Rule) string {
+	if !rule.AppliesTo(sdk.Configuration) && rule.AppliesTo(sdk.VariableDefinitions) {
+		return "terraform.tfvars"
+	}
+
+	return "main.tf"
+}
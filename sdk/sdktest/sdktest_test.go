@@ -0,0 +1,56 @@
+package sdktest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/clintjedwards/tfvet/v2/sdk"
+)
+
+type passwordCheck struct{}
+
+func (c *passwordCheck) Check(content []byte) ([]sdk.RuleError, error) {
+	lintErrors := []sdk.RuleError{}
+	for name, attribute := range sdk.ParseHCL(content).Attributes {
+		if name == "password" {
+			lintErrors = append(lintErrors, sdk.Report(attribute, "Don't commit passwords"))
+		}
+	}
+
+	return lintErrors, nil
+}
+
+var passwordRule = &sdk.Rule{
+	Name:         "Passwords",
+	Short:        "No passwords in variable definitions.",
+	FileKinds:    []sdk.FileKind{sdk.VariableDefinitions},
+	BadExamples:  []string{`password = "hunter2"`},
+	GoodExamples: []string{`region = "us-east-1"`},
+	Check:        &passwordCheck{},
+}
+
+func TestHarness(t *testing.T) {
+	TestExamples(t, passwordRule)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"bad.tfvars":  `password = "hunter2"`,
+		"good.tfvars": `region = "us-east-1"`,
+		// Configuration files aren't linted by the rule; a password variable here is fine.
+		"main.tf": `variable "password" {}`,
+	}
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	TestData(t, passwordRule, dir)
+
+	lintErrors := Lint(t, passwordRule, "prod.tfvars", []byte("region = \"eu\"\npassword = \"hunter2\"\n"), nil)
+	if len(lintErrors) != 1 || lintErrors[0].Location.Start.Line != 2 {
+		t.Fatalf("expected a single lint error on line 2; got %v", lintErrors)
+	}
+}